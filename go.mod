@@ -10,6 +10,7 @@ require (
 	github.com/marco-m/vis v0.0.0-20241230212717-10ce31e0e68f
 	github.com/mattn/go-isatty v0.0.20
 	github.com/rogpeppe/go-internal v1.13.1
+	golang.org/x/sys v0.28.0
 	gotest.tools/v3 v3.5.1
 )
 
@@ -17,6 +18,5 @@ require (
 	github.com/google/go-cmp v0.6.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	golang.org/x/mod v0.22.0 // indirect
-	golang.org/x/sys v0.28.0 // indirect
 	golang.org/x/tools v0.28.0 // indirect
 )
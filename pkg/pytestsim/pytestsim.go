@@ -24,6 +24,7 @@ type msg struct {
 	workerId int
 	name     string
 	status   string
+	crashed  bool
 }
 
 type config struct {
@@ -31,6 +32,7 @@ type config struct {
 	Seed       int64         `help:"Seed for the PRNG (default: current time)." placeholder:"N"`
 	MinDur     time.Duration `help:"min job duration in Go time units (eg: 1h2m3s4ms)." default:"500ms"`
 	MaxDur     time.Duration `help:"max job duration in Go time units (eg: 1h2m3s4ms)." default:"5000ms"`
+	Crash      int           `help:"Simulate a crash of worker N while running its first job (like pytest-xdist)." placeholder:"N"`
 }
 
 func run() error {
@@ -70,7 +72,13 @@ func run() error {
 			} else {
 				terminated++
 				pct := terminated * 100 / numJobs
+				if msg.crashed {
+					fmt.Printf("[gw%d] node down: Not properly terminated\n", msg.workerId)
+				}
 				fmt.Printf("[gw%d] [%d%%] %s %s\n", msg.workerId, pct, msg.status, msg.name)
+				if msg.crashed {
+					fmt.Printf("replacing crashed worker gw%d\n", msg.workerId)
+				}
 			}
 		}
 		printerDone <- true
@@ -109,6 +117,7 @@ func worker(cfg config, workerId int, jobsCh <-chan string, outputCh chan<- msg)
 	// is why we have one per goroutine.
 	seed := uint64(cfg.Seed) + uint64(workerId)
 	rnd := rand.New(rand.NewPCG(seed, seed+100))
+	crash := workerId == cfg.Crash
 	for name := range jobsCh {
 		msg := msg{workerId: workerId, name: name}
 		outputCh <- msg
@@ -118,6 +127,12 @@ func worker(cfg config, workerId int, jobsCh <-chan string, outputCh chan<- msg)
 		time.Sleep(sleep)
 
 		msg.status = "PASSED"
+		if crash {
+			// The replacement worker has the same id and does not crash.
+			msg.status = "FAILED"
+			msg.crashed = true
+			crash = false
+		}
 		outputCh <- msg
 	}
 }
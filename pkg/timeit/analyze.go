@@ -0,0 +1,381 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+)
+
+type analyzeConfig struct {
+	NoColor bool `help:"Disable color output."`
+	Top     int  `placeholder:"N" help:"Show only the N slowest flights (default: all)."`
+
+	File string `arg:"" help:"Output of pytest (with --durations for the durations) or pytest JUnit XML (--junit-xml)."`
+}
+
+// analyzeMain is the main of "timeit analyze", with the arguments after "analyze".
+func analyzeMain(args []string) int {
+	var cfg analyzeConfig
+	parser := kong.Must(&cfg,
+		kong.Name("timeit analyze"),
+		kong.Description("Summarize the tests of an existing pytest log, without running them again."),
+		kong.UsageOnError(),
+		kong.ConfigureHelp(kong.HelpOptions{
+			Compact: false,
+			Summary: true,
+		}))
+	_, err := parser.Parse(args)
+	parser.FatalIfErrorf(err)
+
+	if !isatty.IsTerminal(os.Stdout.Fd()) || cfg.NoColor {
+		color.NoColor = true
+	}
+
+	res, err := analyzeFile(cfg.File, cfg.Top)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "timeit: %s\n", err)
+		return 1
+	}
+	color.New(color.FgMagenta, color.Bold).Fprint(os.Stdout, res.Report())
+	return 0
+}
+
+// An analysis is the tests found in a pytest log.
+type analysis struct {
+	// The tests, in order of appearance. The timing of a test is in its duration
+	// only: started is zero.
+	tests []event
+	// The real time of the run, zero if unknown.
+	real time.Duration
+}
+
+// analyzeFile analyzes file, a pytest output or a pytest JUnit XML, keeping the
+// top slowest tests (all if top is zero).
+func analyzeFile(file string, top int) (Result, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return Result{}, err
+	}
+	var an analysis
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		an, err = analyzeJUnit(bytes.NewReader(data))
+	} else {
+		an, err = analyzePytest(bytes.NewReader(data))
+	}
+	if err != nil {
+		return Result{}, fmt.Errorf("analyzing %s: %s", file, err)
+	}
+	if len(an.tests) == 0 {
+		return Result{}, fmt.Errorf("analyzing %s: no pytest test found", file)
+	}
+
+	records := newRecords()
+	if top <= 0 {
+		top = len(an.tests)
+	}
+	// The statistics give also the counts by status.
+	records.stream = newFlightStats(top)
+	var total time.Duration
+	records.mu.Lock()
+	for _, evt := range layOut(an.tests) {
+		records.appendLanded(evt)
+		total += evt.duration()
+	}
+	records.mu.Unlock()
+	if an.real == 0 {
+		an.real = total
+	}
+	res := Result{
+		Status:    "analysis of " + file,
+		Real:      an.real,
+		records:   records,
+		precision: time.Millisecond,
+	}
+	res.Flights, res.Marks = flightsAndMarks(records)
+	return res, nil
+}
+
+// layOut returns tests one after the other, each starting when the previous one
+// finishes, grouped by file (and class), in order of first appearance. The logs
+// do not tell when each test ran, only its duration: like this, the duration of a
+// group (see writeGroups) is the total of its tests.
+func layOut(tests []event) []event {
+	type laidOut struct {
+		// The order of first appearance of each group of evt, outermost first.
+		key []int
+		evt event
+	}
+	// Group -> order of first appearance.
+	order := make(map[string]int)
+	laid := make([]laidOut, 0, len(tests))
+	for _, evt := range tests {
+		var key []int
+		for j := 1; j < len(evt.path); j++ {
+			group := strings.Join(evt.path[:j], sectionSep)
+			if _, ok := order[group]; !ok {
+				order[group] = len(order)
+			}
+			key = append(key, order[group])
+		}
+		laid = append(laid, laidOut{key: key, evt: evt})
+	}
+	slices.SortStableFunc(laid, func(a, b laidOut) int {
+		return slices.Compare(a.key, b.key)
+	})
+
+	out := make([]event, 0, len(laid))
+	// Not the zero time, that for a group means no flight yet (see node.update).
+	at := time.Unix(0, 0)
+	for _, lo := range laid {
+		evt := lo.evt
+		dur := evt.duration()
+		evt.started = at
+		evt.finished = at.Add(dur)
+		at = evt.finished
+		out = append(out, evt)
+	}
+	return out
+}
+
+// Sections and lines of the output of pytest, besides the ones of the pytest
+// observer.
+var (
+	// The line of a test in verbose mode without pytest-xdist:
+	//  test_fruits.py::test_apple PASSED                [ 33%]
+	//  test_fruits.py::test_coconut SKIPPED (no coconuts) [ 66%]
+	pytestVerboseRe = regexp.MustCompile(
+		`^(?P<name>.+?\.py::.+?) (?P<status>PASSED|FAILED|ERROR|SKIPPED|XFAIL|XPASS|RERUN)(?: \(.*\))?(?: +\[ *\d+%\])?$`)
+	// The header of a section:
+	//  =============== slowest 10 durations ===============
+	pytestSectionRe = regexp.MustCompile(`^=+ (?P<title>.+?) =+$`)
+	// A line of the durations section (see --durations):
+	//  0.52s call     test_fruits.py::test_apple
+	pytestDurationRe = regexp.MustCompile(
+		`^(?P<secs>\d+(?:\.\d+)?)s (?:setup|call|teardown) +(?P<name>.+\.py::.+)$`)
+	// A line of the short test summary info section:
+	//  FAILED test_fruits.py::test_banana - assert 0
+	pytestShortRe = regexp.MustCompile(
+		`^(?P<status>FAILED|ERROR|XFAIL|XPASS) (?P<name>.+?\.py::.+?)(?: - .*)?$`)
+	// The title of the last section, with the real time:
+	//  1 failed, 2 passed in 3.21s (0:00:03)
+	pytestFinalRe = regexp.MustCompile(`^.+ in (?P<secs>\d+(?:\.\d+)?)s(?: \([\d:]+\))?$`)
+	// Color and style escape sequences, when run with --color=yes (for example in CI).
+	sgrRe = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+)
+
+// analyzePytest analyzes the output of pytest. The status of a test comes from
+// the pytest observer (with pytest-xdist), from the verbose mode or from the short
+// test summary; its duration from the durations section (see --durations), summing
+// setup, call and teardown.
+func analyzePytest(r io.Reader) (analysis, error) {
+	var an analysis
+	records := newRecords()
+	obs := newPytestObserver(records, config{})
+	filter := obs.(filterer)
+	durations := make(map[string]time.Duration)
+	var durationsOrder []string
+	shorts := make(map[string]string)
+	var shortsOrder []string
+
+	// All the times are unknown: the observer is only for the statuses.
+	var now time.Time
+	section := ""
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLen)
+	for scanner.Scan() {
+		line := sgrRe.ReplaceAllString(strings.TrimRight(scanner.Text(), "\r"), "")
+		if m := pytestSectionRe.FindStringSubmatch(line); m != nil {
+			section = m[pytestSectionRe.SubexpIndex("title")]
+			if m := pytestFinalRe.FindStringSubmatch(section); m != nil {
+				secs, _ := strconv.ParseFloat(m[pytestFinalRe.SubexpIndex("secs")], 64)
+				an.real = time.Duration(secs * float64(time.Second))
+			}
+			continue
+		}
+		switch {
+		case strings.Contains(section, "durations"):
+			m := pytestDurationRe.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			name := m[pytestDurationRe.SubexpIndex("name")]
+			secs, _ := strconv.ParseFloat(m[pytestDurationRe.SubexpIndex("secs")], 64)
+			if _, ok := durations[name]; !ok {
+				durationsOrder = append(durationsOrder, name)
+			}
+			durations[name] += time.Duration(secs * float64(time.Second))
+		case section == "short test summary info":
+			m := pytestShortRe.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			name := m[pytestShortRe.SubexpIndex("name")]
+			if _, ok := shorts[name]; !ok {
+				shortsOrder = append(shortsOrder, name)
+			}
+			shorts[name] = m[pytestShortRe.SubexpIndex("status")]
+		default:
+			if m := pytestVerboseRe.FindStringSubmatch(line); m != nil {
+				name := m[pytestVerboseRe.SubexpIndex("name")]
+				records.land(name, m[pytestVerboseRe.SubexpIndex("status")], now, 0,
+					pytestPath(name)...)
+				continue
+			}
+			if filter.wants([]byte(line)) {
+				obs.observe(line, now)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return an, err
+	}
+
+	an.tests = slices.Clone(records.landed)
+	// name -> index in an.tests of the last attempt.
+	last := make(map[string]int, len(an.tests))
+	for i, evt := range an.tests {
+		last[evt.name] = i
+	}
+	lastOf := func(name string) *event {
+		i, ok := last[name]
+		if !ok {
+			last[name] = len(an.tests)
+			an.tests = append(an.tests, event{name: name, path: pytestPath(name), attempt: 1})
+			i = last[name]
+		}
+		return &an.tests[i]
+	}
+	for _, name := range shortsOrder {
+		lastOf(name).status = shorts[name]
+	}
+	// With pytest-rerunfailures, the durations of all the attempts go to the last
+	// one.
+	for _, name := range durationsOrder {
+		lastOf(name).finished = time.Time{}.Add(durations[name])
+	}
+	return an, nil
+}
+
+// The elements of a JUnit XML written by pytest (see --junit-xml), that is:
+//
+//	<testsuites>
+//	  <testsuite name="pytest" tests="2" time="3.210">
+//	    <testcase classname="tests.test_fruits.TestApple" name="test_color" time="0.52">
+//	      <failure message="assert 0">...</failure>
+//	    </testcase>
+//	    ...
+type (
+	junitSuite struct {
+		Time string `xml:"time,attr"`
+	}
+	junitCase struct {
+		ClassName string    `xml:"classname,attr"`
+		Name      string    `xml:"name,attr"`
+		File      string    `xml:"file,attr"`
+		Time      string    `xml:"time,attr"`
+		Failure   *struct{} `xml:"failure"`
+		Error     *struct{} `xml:"error"`
+		Skipped   *struct {
+			Type string `xml:"type,attr"`
+		} `xml:"skipped"`
+	}
+)
+
+// analyzeJUnit analyzes a JUnit XML written by pytest. The real time is the
+// total of the test suites.
+func analyzeJUnit(r io.Reader) (analysis, error) {
+	var an analysis
+	attempts := make(map[string]int)
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return an, nil
+		}
+		if err != nil {
+			return an, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "testsuite":
+			// The children of the suite are decoded as tokens.
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "time" {
+					secs, err := strconv.ParseFloat(attr.Value, 64)
+					if err != nil {
+						return an, fmt.Errorf("testsuite: time %q: %s", attr.Value, err)
+					}
+					an.real += time.Duration(secs * float64(time.Second))
+				}
+			}
+		case "testcase":
+			var tc junitCase
+			if err := dec.DecodeElement(&tc, &start); err != nil {
+				return an, err
+			}
+			secs, err := strconv.ParseFloat(tc.Time, 64)
+			if err != nil {
+				return an, fmt.Errorf("testcase %s: time %q: %s", tc.Name, tc.Time, err)
+			}
+			name := junitNodeID(tc.ClassName, tc.Name, tc.File)
+			attempts[name]++
+			an.tests = append(an.tests, event{
+				name:     name,
+				status:   junitStatus(tc),
+				path:     pytestPath(name),
+				attempt:  attempts[name],
+				finished: time.Time{}.Add(time.Duration(secs * float64(time.Second))),
+			})
+		}
+	}
+}
+
+// junitNodeID returns the pytest node ID of a test case. The classname is the
+// dotted path of the module, followed by the classes (whose names start with
+// "Test", the pytest default). The file, if not empty (junit_family=xunit1), is
+// the path of the module.
+func junitNodeID(classname string, name string, file string) string {
+	parts := strings.Split(classname, ".")
+	i := slices.IndexFunc(parts, func(p string) bool { return strings.HasPrefix(p, "Test") })
+	if i < 0 {
+		i = len(parts)
+	}
+	if file == "" {
+		file = strings.Join(parts[:i], "/") + ".py"
+	}
+	return strings.Join(append(append([]string{file}, parts[i:]...), name), "::")
+}
+
+// junitStatus returns the status of tc, in the vocabulary of pytest.
+func junitStatus(tc junitCase) string {
+	switch {
+	case tc.Failure != nil:
+		return "FAILED"
+	case tc.Error != nil:
+		return "ERROR"
+	case tc.Skipped != nil && tc.Skipped.Type == "pytest.xfail":
+		return "XFAIL"
+	case tc.Skipped != nil:
+		return "SKIPPED"
+	}
+	return "PASSED"
+}
@@ -0,0 +1,75 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestJUnitNodeID(t *testing.T) {
+	type testCase struct {
+		name      string
+		classname string
+		file      string
+		want      string
+	}
+
+	test := func(t *testing.T, tc testCase) {
+		assert.Equal(t, junitNodeID(tc.classname, "test_x", tc.file), tc.want)
+	}
+
+	testCases := []testCase{
+		{
+			name:      "module",
+			classname: "test_fruits",
+			want:      "test_fruits.py::test_x",
+		},
+		{
+			name:      "package and classes",
+			classname: "tests.fruits.test_apple.TestApple.TestColor",
+			want:      "tests/fruits/test_apple.py::TestApple::TestColor::test_x",
+		},
+		{
+			name:      "file of xunit1",
+			classname: "tests.test_apple.TestApple",
+			file:      "tests/test_apple.py",
+			want:      "tests/test_apple.py::TestApple::test_x",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) { test(t, tc) })
+	}
+}
+
+func TestAnalyzePytestRerun(t *testing.T) {
+	log := `
+[gw0] [ 50%] PASSED test_fruits.py::test_apple
+[gw1] [100%] RERUN test_fruits.py::test_banana
+[gw1] [100%] PASSED test_fruits.py::test_banana
+========== slowest durations ==========
+2.00s call     test_fruits.py::test_banana
+1.00s call     test_fruits.py::test_banana
+0.50s call     test_fruits.py::test_apple
+========== 2 passed, 1 rerun in 3.60s ==========
+`
+	an, err := analyzePytest(strings.NewReader(log))
+	assert.NilError(t, err)
+	var got []string
+	for _, evt := range layOut(an.tests) {
+		got = append(got, fmt.Sprintf("%s %s %v", evt.displayName(), evt.status,
+			evt.duration()))
+	}
+	// The durations of all the attempts go to the last one.
+	assert.DeepEqual(t, got, []string{
+		"test_fruits.py::test_apple PASSED 500ms",
+		"test_fruits.py::test_banana RERUN 0s",
+		"test_fruits.py::test_banana (attempt 2) PASSED 3s",
+	})
+	assert.Equal(t, an.real.String(), "3.6s")
+}
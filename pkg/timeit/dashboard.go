@@ -0,0 +1,310 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// A dashboard redraws the ticker in place, in the bottom rows of the terminal,
+// while the output of the command scrolls in the rows above it (a scrolling
+// region). It is for long runs, where appending a ticker each time would flood
+// the scrollback (see --tui).
+type dashboard struct {
+	// write writes to the terminal, atomically with respect to the output of the
+	// command (see mux.WriteNow).
+	write func(p []byte)
+	size  func() (rows int, cols int, err error)
+	rows  int
+	cols  int
+	// Number of rows at the bottom of the terminal reserved to the dashboard. Zero
+	// if the terminal became too small: nothing is drawn.
+	height int
+}
+
+const (
+	// Below this number of rows, the dashboard is not drawn.
+	dashboardMinRows = 12
+	// The dashboard takes half of the rows, up to this number.
+	dashboardMaxHeight = 16
+	// Number of recent landings and failures shown.
+	dashboardRecent = 3
+)
+
+// VT100 escape sequences.
+const (
+	escSaveCursor    = "\x1b7"
+	escRestoreCursor = "\x1b8"
+	escClearLine     = "\x1b[2K"
+	escResetRegion   = "\x1b[r"
+	// Scrolling region from row 1 to row %d. Moves the cursor to the top left.
+	escSetRegion = "\x1b[1;%dr"
+	escMoveTo    = "\x1b[%d;1H"
+	escCursorUp  = "\x1b[%dA"
+)
+
+func newDashboard(write func(p []byte), size func() (int, int, error)) *dashboard {
+	return &dashboard{write: write, size: size}
+}
+
+// dashboardHeight returns the number of rows of the dashboard in a terminal with
+// rows rows.
+func dashboardHeight(rows int) int {
+	if rows < dashboardMinRows {
+		return 0
+	}
+	return min(rows/2, dashboardMaxHeight)
+}
+
+// setup reserves the bottom rows of the terminal to the dashboard. It must be
+// called before the command writes its output, since it moves the cursor. It
+// returns an error if the terminal is too small for the dashboard.
+func (dash *dashboard) setup() error {
+	rows, cols, err := dash.size()
+	if err != nil {
+		return err
+	}
+	dash.rows, dash.cols = rows, cols
+	dash.height = dashboardHeight(rows)
+	if dash.height == 0 {
+		return fmt.Errorf("dashboard: terminal too small: %d rows, want at least %d",
+			rows, dashboardMinRows)
+	}
+	var bld strings.Builder
+	// Make room, scrolling up the screen if needed, and go back.
+	bld.WriteString(strings.Repeat("\n", dash.height))
+	fmt.Fprintf(&bld, escCursorUp, dash.height)
+	bld.WriteString(escSaveCursor)
+	fmt.Fprintf(&bld, escSetRegion, dash.rows-dash.height)
+	bld.WriteString(escRestoreCursor)
+	dash.write([]byte(bld.String()))
+	return nil
+}
+
+// draw draws lines in the rows of the dashboard, adapting to the current size of
+// the terminal.
+func (dash *dashboard) draw(lines func(height int, cols int) []string) {
+	var bld strings.Builder
+	bld.WriteString(escSaveCursor)
+	if rows, cols, err := dash.size(); err == nil && (rows != dash.rows || cols != dash.cols) {
+		dash.rows, dash.cols = rows, cols
+		dash.height = dashboardHeight(rows)
+		if dash.height == 0 {
+			bld.WriteString(escResetRegion)
+		} else {
+			fmt.Fprintf(&bld, escSetRegion, dash.rows-dash.height)
+		}
+	}
+	if dash.height > 0 {
+		for i, line := range lines(dash.height, dash.cols) {
+			fmt.Fprintf(&bld, escMoveTo, dash.rows-dash.height+1+i)
+			bld.WriteString(escClearLine)
+			bld.WriteString(line)
+		}
+	}
+	bld.WriteString(escRestoreCursor)
+	dash.write([]byte(bld.String()))
+}
+
+// teardown clears the dashboard and gives back its rows to the output.
+func (dash *dashboard) teardown() {
+	if dash.height == 0 {
+		return
+	}
+	var bld strings.Builder
+	bld.WriteString(escSaveCursor)
+	bld.WriteString(escResetRegion)
+	for row := dash.rows - dash.height + 1; row <= dash.rows; row++ {
+		fmt.Fprintf(&bld, escMoveTo, row)
+		bld.WriteString(escClearLine)
+	}
+	bld.WriteString(escRestoreCursor)
+	dash.write([]byte(bld.String()))
+	dash.height = 0
+}
+
+// setupDashboard draws the dashboard each dur, until stop is closed. It then
+// clears the dashboard and closes the returned channel. If log is not nil, it
+// receives also the ticker, as setupPeriodicTicker would print it.
+func setupDashboard(stop <-chan struct{}, t0 time.Time, dur time.Duration, summarize bool,
+	records *records, dash *dashboard, log printFn,
+) <-chan struct{} {
+	done := make(chan struct{})
+	ticker := time.NewTicker(dur)
+	tw := newTickerWriter()
+	draw := func(now time.Time) {
+		snap := takeSnapshot(records)
+		dash.draw(func(height int, cols int) []string {
+			return dashboardLines(snap, now, t0, dur, height, cols)
+		})
+	}
+
+	go func() {
+		defer close(done)
+		draw(time.Now())
+		for {
+			select {
+			case <-stop:
+				ticker.Stop()
+				dash.teardown()
+				return
+			case now := <-ticker.C:
+				draw(now)
+				if log != nil {
+					log("%s\n", tw.ticker(now, t0, dur, summarize, records))
+				}
+			}
+		}
+	}()
+
+	return done
+}
+
+// A snapshot is a copy of the records shown by the dashboard.
+type snapshot struct {
+	flying   []event
+	recent   []event
+	failures []event
+	failed   int
+	done     int
+	total    int
+	crashed  []string
+}
+
+func takeSnapshot(records *records) snapshot {
+	records.mu.Lock()
+	defer records.mu.Unlock()
+	snap := snapshot{
+		flying:   make([]event, 0, len(records.flying)),
+		recent:   slices.Clone(records.recent),
+		failures: slices.Clone(records.failures),
+		failed:   records.failed,
+		done:     records.done,
+		total:    records.total,
+		crashed:  crashedWorkers(records.crashes),
+	}
+	for _, evt := range records.flying {
+		snap.flying = append(snap.flying, evt)
+	}
+	sort.Slice(snap.flying, func(i, j int) bool {
+		return snap.flying[i].started.Before(snap.flying[j].started)
+	})
+	return snap
+}
+
+// dashboardLines returns the height lines of the dashboard at time now, each at
+// most cols wide: a status line, the flights in flight (the oldest first), the
+// recent failures and the recent landings.
+func dashboardLines(snap snapshot, now time.Time, t0 time.Time, dur time.Duration,
+	height int, cols int,
+) []string {
+	elapsed := now.Sub(t0)
+	dur100 := dur / 100
+	chroma := color.New(color.FgMagenta, color.Bold)
+	red := color.New(color.FgRed)
+
+	status := []string{fmt.Sprintf("timeit: running for %s", elapsed.Truncate(dur))}
+	if snap.total > 0 {
+		status = append(status,
+			"progress: "+progress(snap.done, snap.total, elapsed, dur))
+	}
+	if snap.failed > 0 {
+		status = append(status, fmt.Sprintf("failed: %d", snap.failed))
+	}
+	if len(snap.crashed) > 0 {
+		status = append(status, "crashed workers: "+strings.Join(snap.crashed, ", "))
+	}
+	lines := []string{chroma.Sprint(truncate(strings.Join(status, " | "), cols))}
+
+	flight := func(evt event, d time.Duration, c *color.Color) string {
+		line := fmt.Sprintf("    %8v  ", d.Truncate(dur100))
+		if evt.status != "" {
+			line += evt.status + "  "
+		}
+		line = truncate(line+evt.displayName(), cols)
+		if c != nil {
+			return c.Sprint(line)
+		}
+		return line
+	}
+
+	var landed []string
+	if n := min(len(snap.recent), dashboardRecent); n > 0 {
+		landed = append(landed, truncate("landed:", cols))
+		for _, evt := range snap.recent[len(snap.recent)-n:] {
+			var c *color.Color
+			if isFailure(evt.status) {
+				c = red
+			}
+			landed = append(landed, flight(evt, evt.duration(), c))
+		}
+	}
+	var failed []string
+	if n := min(len(snap.failures), dashboardRecent); n > 0 {
+		failed = append(failed, truncate(fmt.Sprintf("failed (%d):", snap.failed), cols))
+		for _, evt := range snap.failures[len(snap.failures)-n:] {
+			failed = append(failed, flight(evt, evt.duration(), red))
+		}
+	}
+
+	// If the terminal is short, the sections shrink to fit, keeping their most
+	// recent flights: first the landed flights, then the failed ones, leaving at
+	// least a row for the flights in flight.
+	rest := height - len(lines) - 1 - min(len(snap.flying), 1)
+	failed = fitSection(failed, rest)
+	landed = fitSection(landed, rest-len(failed))
+
+	// The flights in flight take the remaining rows.
+	room := max(height-len(lines)-len(landed)-len(failed)-1, 0)
+	lines = append(lines, truncate(fmt.Sprintf("in-flight (%d):", len(snap.flying)), cols))
+	for i, evt := range snap.flying {
+		if i == room-1 && len(snap.flying) > room {
+			lines = append(lines,
+				truncate(fmt.Sprintf("    ... and %d more", len(snap.flying)-i), cols))
+			break
+		}
+		if i == room {
+			break
+		}
+		lines = append(lines, flight(evt, now.Sub(evt.started), nil))
+	}
+	lines = append(lines, failed...)
+	lines = append(lines, landed...)
+
+	// Always height lines, to clear the rows of the previous draw.
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return lines[:height]
+}
+
+// fitSection returns section, a title followed by flights, shrunk to at most
+// rows by dropping its oldest flights, or nil if not even a flight fits.
+func fitSection(section []string, rows int) []string {
+	if len(section) <= rows {
+		return section
+	}
+	if rows < 2 {
+		return nil
+	}
+	return append(section[:1], section[len(section)-rows+1:]...)
+}
+
+// truncate returns s, truncated to at most cols runes.
+func truncate(s string, cols int) string {
+	if cols <= 0 || len(s) <= cols {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= cols {
+		return s
+	}
+	return string(runes[:cols])
+}
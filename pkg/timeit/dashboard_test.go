@@ -0,0 +1,165 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"gotest.tools/v3/assert"
+)
+
+func TestDashboardLines(t *testing.T) {
+	defer func(noColor bool) { color.NoColor = noColor }(color.NoColor)
+	color.NoColor = true
+
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	flight := func(name string, status string, started int, finished int) event {
+		evt := event{name: name, status: status, attempt: 1,
+			started: t0.Add(time.Duration(started) * time.Second)}
+		if finished > 0 {
+			evt.finished = t0.Add(time.Duration(finished) * time.Second)
+		}
+		return evt
+	}
+	snap := snapshot{
+		flying: []event{
+			flight("test_a", "", 10, 0),
+			flight("test_b", "", 20, 0),
+			flight("test_c", "", 30, 0),
+			flight("test_d", "", 40, 0),
+		},
+		recent: []event{
+			flight("test_x", "PASSED", 1, 2),
+			flight("test_y_with_a_long_name_that_does_not_fit", "FAILED", 2, 4),
+		},
+		failures: []event{
+			flight("test_y_with_a_long_name_that_does_not_fit", "FAILED", 2, 4),
+		},
+		failed: 1,
+		done:   2,
+		total:  10,
+	}
+
+	lines := dashboardLines(snap, t0.Add(90*time.Second), t0, 10*time.Second, 10, 50)
+
+	want := []string{
+		"timeit: running for 1m30s | progress: 2/10 (20%), ",
+		"in-flight (4):",
+		"       1m20s  test_a",
+		"       1m10s  test_b",
+		"    ... and 2 more",
+		"failed (1):",
+		"          2s  FAILED  test_y_with_a_long_name_that",
+		"landed:",
+		"          1s  PASSED  test_x",
+		"          2s  FAILED  test_y_with_a_long_name_that",
+	}
+	assert.DeepEqual(t, lines, want)
+}
+
+func TestDashboardLinesSmallHeight(t *testing.T) {
+	defer func(noColor bool) { color.NoColor = noColor }(color.NoColor)
+	color.NoColor = true
+
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var snap snapshot
+	for i := range 10 {
+		snap.flying = append(snap.flying, event{name: fmt.Sprintf("test_run_%d", i),
+			attempt: 1, started: t0})
+		evt := event{name: fmt.Sprintf("test_fail_%d", i), status: "FAILED",
+			attempt: 1, started: t0, finished: t0.Add(time.Second)}
+		snap.recent = append(snap.recent, evt)
+		snap.failures = append(snap.failures, evt)
+	}
+	snap.failed = 10
+
+	lines := dashboardLines(snap, t0.Add(5*time.Second), t0, time.Second, 6, 80)
+
+	want := []string{
+		"timeit: running for 5s | failed: 10",
+		"in-flight (10):",
+		"    ... and 10 more",
+		"failed (10):",
+		"          1s  FAILED  test_fail_8",
+		"          1s  FAILED  test_fail_9",
+	}
+	assert.DeepEqual(t, lines, want)
+}
+
+func TestDashboardLinesPadsToHeight(t *testing.T) {
+	defer func(noColor bool) { color.NoColor = noColor }(color.NoColor)
+	color.NoColor = true
+
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	lines := dashboardLines(snapshot{}, t0.Add(time.Second), t0, time.Second, 4, 80)
+
+	assert.DeepEqual(t, lines, []string{"timeit: running for 1s", "in-flight (0):", "", ""})
+}
+
+func TestDashboardDraw(t *testing.T) {
+	var term strings.Builder
+	rows := 20
+	dash := newDashboard(func(p []byte) { term.Write(p) },
+		func() (int, int, error) { return rows, 80, nil })
+	lines := func(height int, cols int) []string {
+		return make([]string, height)
+	}
+
+	assert.NilError(t, dash.setup())
+	assert.Equal(t, dash.height, 10)
+	assert.Equal(t, term.String(), strings.Repeat("\n", 10)+"\x1b[10A"+"\x1b7\x1b[1;10r\x1b8")
+
+	term.Reset()
+	rows = 16
+	dash.draw(lines)
+	assert.Equal(t, dash.height, 8)
+	assert.Assert(t, strings.HasPrefix(term.String(), "\x1b7\x1b[1;8r\x1b[9;1H\x1b[2K"),
+		"%q", term.String())
+	assert.Assert(t, strings.HasSuffix(term.String(), "\x1b[16;1H\x1b[2K\x1b8"),
+		"%q", term.String())
+
+	term.Reset()
+	dash.teardown()
+	assert.Assert(t, strings.HasPrefix(term.String(), "\x1b7\x1b[r\x1b[9;1H\x1b[2K"),
+		"%q", term.String())
+	assert.Assert(t, strings.HasSuffix(term.String(), "\x1b[16;1H\x1b[2K\x1b8"),
+		"%q", term.String())
+}
+
+func TestDashboardTooSmall(t *testing.T) {
+	var term strings.Builder
+	dash := newDashboard(func(p []byte) { term.Write(p) },
+		func() (int, int, error) { return dashboardMinRows - 1, 80, nil })
+
+	assert.ErrorContains(t, dash.setup(), "terminal too small")
+	dash.teardown()
+	assert.Equal(t, term.String(), "")
+}
+
+func TestDashboardLogsTicker(t *testing.T) {
+	var term strings.Builder
+	var log bytes.Buffer
+	dash := newDashboard(func(p []byte) { term.Write(p) },
+		func() (int, int, error) { return 24, 80, nil })
+	assert.NilError(t, dash.setup())
+	t0 := time.Now()
+	tr := newTranscript(&log, t0, []string{"pytest"})
+	stop := make(chan struct{})
+
+	done := setupDashboard(stop, t0, 10*time.Millisecond, true, newRecords(), dash,
+		func(format string, a ...any) { tr.text(tagTimeit, fmt.Sprintf(format, a...)) })
+	time.Sleep(35 * time.Millisecond)
+	close(stop)
+	<-done
+	assert.NilError(t, tr.flush())
+
+	assert.Assert(t, strings.Contains(log.String(), " timeit timeit ticker: running for "),
+		"%s", log.String())
+	assert.Assert(t, !strings.Contains(log.String(), "\x1b["), "%q", log.String())
+}
@@ -0,0 +1,7 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+// Exported only to the tests of package timeit_test.
+var Progress = progress
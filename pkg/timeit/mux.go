@@ -0,0 +1,149 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+)
+
+// muxMaxDelay is how long the output of timeit can wait for the output of the
+// command to reach a line boundary.
+const muxMaxDelay = time.Second
+
+// A mux owns the terminal: it serializes the output of the command (stdout and
+// stderr) and the output of timeit (written to stderr), so that they never
+// interleave in the middle of a line.
+//
+// The output of timeit is written when the output of the command is at a line
+// boundary. If the command stays in the middle of a line (for example, waiting at
+// a prompt), the output of timeit is written anyway after maxDelay.
+type mux struct {
+	mu     sync.Mutex
+	stdout io.Writer
+	stderr io.Writer
+	// For each stream of the command, true if the last byte written was not a
+	// newline.
+	midLine [2]bool
+	// The output of timeit waiting for a line boundary.
+	pending  []byte
+	timer    *time.Timer
+	maxDelay time.Duration
+	// Once closed, the output of timeit is written immediately.
+	closed bool
+}
+
+const (
+	muxStdout = iota
+	muxStderr
+)
+
+func newMux(stdout io.Writer, stderr io.Writer) *mux {
+	return &mux{stdout: stdout, stderr: stderr, maxDelay: muxMaxDelay}
+}
+
+// Stdout returns the writer for the stdout of the command.
+func (m *mux) Stdout() io.Writer {
+	return &muxWriter{mux: m, stream: muxStdout, dst: m.stdout}
+}
+
+// Stderr returns the writer for the stderr of the command.
+func (m *mux) Stderr() io.Writer {
+	return &muxWriter{mux: m, stream: muxStderr, dst: m.stderr}
+}
+
+// Write writes p, output of timeit, to stderr, as soon as the output of the
+// command is at a line boundary. p should be one or more complete lines.
+func (m *mux) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed || (len(m.pending) == 0 && m.atLineBoundary()) {
+		return m.stderr.Write(p)
+	}
+	m.pending = append(m.pending, p...)
+	if m.timer == nil {
+		m.timer = time.AfterFunc(m.maxDelay, func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			m.writePending()
+		})
+	}
+	return len(p), nil
+}
+
+// WriteNow writes p, output of timeit, to stderr immediately, also in the middle
+// of a line of the command. p must leave the cursor where it found it (see
+// dashboard).
+func (m *mux) WriteNow(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stderr.Write(p)
+}
+
+// Close writes the pending output of timeit, if any. From now on, the output of
+// timeit is written immediately. Call Close once the output of the command has
+// been drained.
+func (m *mux) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.writePending()
+	m.closed = true
+	return nil
+}
+
+// atLineBoundary returns true if no stream of the command is in the middle of a
+// line. Must be called with m.mu held.
+func (m *mux) atLineBoundary() bool {
+	return !m.midLine[muxStdout] && !m.midLine[muxStderr]
+}
+
+// writePending writes the pending output of timeit. Must be called with m.mu held.
+func (m *mux) writePending() {
+	if m.timer != nil {
+		m.timer.Stop()
+		m.timer = nil
+	}
+	if len(m.pending) > 0 {
+		m.stderr.Write(m.pending)
+		m.pending = m.pending[:0]
+	}
+}
+
+// A muxWriter writes one stream of the command output to dst, through mux.
+type muxWriter struct {
+	mux    *mux
+	stream int
+	dst    io.Writer
+}
+
+func (w *muxWriter) Write(p []byte) (int, error) {
+	m := w.mux
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	if len(m.pending) > 0 {
+		// Write up to the last line boundary, then the output of timeit.
+		if i := bytes.LastIndexByte(p, '\n'); i >= 0 {
+			k, err := w.dst.Write(p[:i+1])
+			n += k
+			if err != nil {
+				return n, err
+			}
+			m.midLine[w.stream] = false
+			if m.atLineBoundary() {
+				m.writePending()
+			}
+			p = p[i+1:]
+		}
+	}
+	if len(p) == 0 {
+		return n, nil
+	}
+	k, err := w.dst.Write(p)
+	n += k
+	m.midLine[w.stream] = p[len(p)-1] != '\n'
+	return n, err
+}
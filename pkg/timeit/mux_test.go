@@ -0,0 +1,84 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/poll"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (sb *syncBuffer) Write(p []byte) (int, error) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.buf.Write(p)
+}
+
+func (sb *syncBuffer) String() string {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.buf.String()
+}
+
+func TestMuxWritesAtLineBoundaries(t *testing.T) {
+	// As on a terminal, stdout and stderr end up in the same place.
+	var term syncBuffer
+	m := newMux(&term, &term)
+	m.maxDelay = time.Hour
+
+	fmt.Fprint(m, "<ticker 1>\n")
+	fmt.Fprint(m.Stdout(), "out 1\nout 2 begins")
+	fmt.Fprint(m, "<ticker 2>\n")
+	fmt.Fprint(m.Stderr(), "err 1\n")
+	fmt.Fprint(m, "<ticker 3>\n")
+	assert.Equal(t, term.String(), "<ticker 1>\nout 1\nout 2 begins"+"err 1\n")
+
+	fmt.Fprint(m.Stdout(), " and ends\nout 3 begins")
+	fmt.Fprint(m.Stdout(), " and ends\n")
+	m.Close()
+	fmt.Fprint(m, "<results>\n")
+
+	assert.Equal(t, term.String(), "<ticker 1>\nout 1\nout 2 begins"+"err 1\n"+
+		" and ends\n<ticker 2>\n<ticker 3>\nout 3 begins and ends\n<results>\n")
+}
+
+func TestMuxCloseWritesPending(t *testing.T) {
+	var term syncBuffer
+	m := newMux(&term, &term)
+	m.maxDelay = time.Hour
+
+	fmt.Fprint(m.Stderr(), "prompt: ")
+	fmt.Fprint(m, "<ticker>\n")
+	assert.Equal(t, term.String(), "prompt: ")
+
+	m.Close()
+	assert.Equal(t, term.String(), "prompt: <ticker>\n")
+}
+
+func TestMuxMaxDelay(t *testing.T) {
+	var term syncBuffer
+	m := newMux(&term, &term)
+	m.maxDelay = 10 * time.Millisecond
+
+	fmt.Fprint(m.Stdout(), "prompt: ")
+	fmt.Fprint(m, "<ticker>\n")
+
+	poll.WaitOn(t, func(poll.LogT) poll.Result {
+		if term.String() == "prompt: <ticker>\n" {
+			return poll.Success()
+		}
+		return poll.Continue("got %q", term.String())
+	}, poll.WithTimeout(time.Second), poll.WithDelay(5*time.Millisecond))
+}
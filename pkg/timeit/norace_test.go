@@ -0,0 +1,8 @@
+//go:build !race
+
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+const raceEnabled = false
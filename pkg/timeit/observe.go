@@ -4,66 +4,392 @@
 package timeit
 
 import (
-	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"os"
 	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
+// An observer extracts flights from the output of the child, one line at a time,
+// and stores them in records.
+type observer interface {
+	observe(line string, now time.Time)
+}
+
+// A starter is an observer that needs to know when the command started, before
+// its first line.
+type starter interface {
+	start(t0 time.Time)
+}
+
+// A finisher is an observer that has more work to do once the command has
+// terminated and its output has been drained.
+type finisher interface {
+	finish(t0 time.Time) error
+}
+
+// A filterer is an observer that can tell cheaply, without allocating, if a line
+// could be of interest to it. The other lines are not passed to observe, saving
+// the cost of converting them to strings, reading the clock and matching the
+// regexps, which for chatty commands is most of the lines. wants must be safe for
+// concurrent use.
+type filterer interface {
+	wants(line []byte) bool
+}
+
+// observers maps each format supported by --observe to its constructor.
+var observers = map[string]func(*records, config) observer{
+	"ansible":  newAnsibleObserver,
+	"buildkit": newBuildkitObserver,
+	"ctest":    newCtestObserver,
+	"json":     newJSONObserver,
+	"libtest":  newLibtestObserver,
+	"make":     newMakeObserver,
+	"ninja":    newNinjaObserver,
+	"pytest":   newPytestObserver,
+	"sections": newSectionsObserver,
+	"tap":      newTapObserver,
+}
+
+// stderrObservers are the formats of the tools that print to stderr what the
+// observer needs (docker build its progress, make its errors, programs their
+// structured logs). For these formats the observer reads also the stderr of the
+// command; for the others, stderr is left alone.
+var stderrObservers = map[string]bool{
+	"buildkit": true,
+	"json":     true,
+	"make":     true,
+}
+
+// observerNames returns the sorted list of formats supported by --observe.
+func observerNames() []string {
+	names := make([]string, 0, len(observers))
+	for name := range observers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lockedObserver serializes the calls to an observer fed by more than one stream.
+type lockedObserver struct {
+	mu  sync.Mutex
+	obs observer
+}
+
+func (lo *lockedObserver) observe(line string, now time.Time) {
+	lo.mu.Lock()
+	defer lo.mu.Unlock()
+	lo.obs.observe(line, now)
+}
+
+func (lo *lockedObserver) wants(line []byte) bool {
+	if f, ok := lo.obs.(filterer); ok {
+		return f.wants(line)
+	}
+	return true
+}
+
+// observeOutput copies stream src (named streamName) to dst, byte for byte, and
+// feeds each line of a copy to obs, until src is drained. Each chunk read from
+// src is written to dst as soon as it is read, so that the output of the command
+// is not delayed. If writing to dst fails, src is still drained and observed,
+// so that the command does not block on a full pipe, and the first write error
+// is returned.
+func observeOutput(obs observer, streamName string, src io.Reader, dst io.Writer) error {
+	filter, _ := obs.(filterer)
+	lines := &lineWriter{fn: func(line []byte) {
+		if filter == nil || filter.wants(line) {
+			obs.observe(string(line), time.Now())
+		}
+	}}
+	var writeErr error
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if writeErr == nil {
+				if _, err := dst.Write(buf[:n]); err != nil {
+					writeErr = fmt.Errorf("writing %s: %s", streamName, err)
+				}
+			}
+			lines.Write(buf[:n])
+		}
+		if err != nil {
+			lines.flush()
+			// Closed by Run, to stop waiting for the output (see outputGrace).
+			if !errors.Is(err, io.EOF) && !errors.Is(err, os.ErrClosed) {
+				return fmt.Errorf("reading from %s: %s", streamName, err)
+			}
+			return writeErr
+		}
+	}
+}
+
+// maxLineLen is the maximum length of a line passed by a lineWriter.
+const maxLineLen = 1024 * 1024
+
+// lineWriter is an io.Writer that calls fn for each line written to it, without
+// the line terminator. A line is terminated by "\n", "\r\n" or a lone "\r", as
+// used by progress bars to redraw a line in place. A line longer than maxLineLen
+// is truncated. fn must not retain line.
+type lineWriter struct {
+	fn func(line []byte)
+	// The beginning of a line, seen in previous writes.
+	partial []byte
+	// True if the last byte written was "\r": a "\n" following it does not
+	// terminate another line.
+	cr bool
+}
+
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		if lw.cr && p[0] == '\n' {
+			p = p[1:]
+		}
+		lw.cr = false
+		i := indexEOL(p)
+		if i < 0 {
+			lw.appendPartial(p)
+			break
+		}
+		lw.cr = p[i] == '\r'
+		if len(lw.partial) == 0 {
+			// Fast path: the line is in p, no need to copy it.
+			lw.fn(p[:min(i, maxLineLen)])
+		} else {
+			lw.appendPartial(p[:i])
+			lw.fn(lw.partial)
+			lw.partial = lw.partial[:0]
+		}
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// flush passes the last line, if not terminated.
+func (lw *lineWriter) flush() {
+	if len(lw.partial) > 0 {
+		lw.fn(lw.partial)
+		lw.partial = lw.partial[:0]
+	}
+}
+
+func (lw *lineWriter) appendPartial(p []byte) {
+	room := maxLineLen - len(lw.partial)
+	lw.partial = append(lw.partial, p[:min(len(p), room)]...)
+}
+
+// indexEOL returns the index of the first "\n" or "\r" in p, or -1.
+func indexEOL(p []byte) int {
+	i := bytes.IndexByte(p, '\n')
+	if i < 0 {
+		i = len(p)
+	}
+	if j := bytes.IndexByte(p[:i], '\r'); j >= 0 {
+		return j
+	}
+	if i == len(p) {
+		return -1
+	}
+	return i
+}
+
 // (?:re)        non-capturing group
 // (?P<name>re)  named and numbered capturing group
 
 var pat = `^(?:(?P<gw>\[gw\d+]) +(?P<pct>\[ *\d+%]) +(?P<status>[A-Z]+) +)?(?P<name>.+\.py::.+)$`
 var pytestRe = regexp.MustCompile(pat)
 
-func observePytest(records *records, stdout io.Reader, out printFn) {
-	scanOut := bufio.NewScanner(stdout)
-	groupNames := pytestRe.SubexpNames()
-	for scanOut.Scan() {
-		line := scanOut.Text()
-		now := time.Now()
-		fmt.Println(line) // FIXME this can still race...
+var (
+	pytestGwIdx     = pytestRe.SubexpIndex("gw")
+	pytestStatusIdx = pytestRe.SubexpIndex("status")
+	pytestNameIdx   = pytestRe.SubexpIndex("name")
+)
+
+// submatch returns submatch i of s, given the indexes m returned by
+// FindStringSubmatchIndex, or "" if it did not match.
+func submatch(s string, m []int, i int) string {
+	if m[2*i] < 0 {
+		return ""
+	}
+	return s[m[2*i]:m[2*i+1]]
+}
 
-		matches := pytestRe.FindStringSubmatch(line)
+// Lines printed by pytest-xdist when a worker crashes. The test that was running
+// on the worker is reported as FAILED between the two lines, if at all.
+var (
+	xdistDownRe    = regexp.MustCompile(`^\[(?P<gw>gw\d+)\] node down: (?P<reason>.*?)\s*$`)
+	xdistReplaceRe = regexp.MustCompile(`^replacing crashed worker (?P<gw>gw\d+)\s*$`)
+)
 
-		if matches == nil {
-			continue
-		}
+type pytestObserver struct {
+	records *records
+	// Workers that crashed and have not been replaced yet.
+	down map[string]bool
+	// The test that each worker is running, as far as we can tell (see assign).
+	current map[string]string
+	// Workers that landed a test and have not been assigned the next one yet, at
+	// most once each, in landing order.
+	idle []string
+	// Tests that took off while no worker was idle, in takeoff order.
+	unassigned []string
+	// The test that each worker that went down was running, as far as we can
+	// tell, to land as CRASHED if pytest-xdist does not report it as FAILED.
+	suspects map[string]pytestSuspect
+}
 
-		// Construct a lookup map for the named groups. In this particular case this is
-		// not needed since we look up only one name, but it shows the idiomatic way.
-		groups := make(map[string]string, len(groupNames))
-		for i := 1; i < len(matches); i++ {
-			if matches[i] != "" {
-				groups[groupNames[i]] = matches[i]
-			}
+// A pytestSuspect is a test that was running on a worker when it went down.
+type pytestSuspect struct {
+	name string
+	at   time.Time
+}
+
+func newPytestObserver(records *records, cfg config) observer {
+	return &pytestObserver{
+		records:  records,
+		down:     make(map[string]bool),
+		current:  make(map[string]string),
+		suspects: make(map[string]pytestSuspect),
+	}
+}
+
+func (obs *pytestObserver) wants(line []byte) bool {
+	return bytes.Contains(line, []byte(".py::")) ||
+		bytes.HasPrefix(line, []byte("[gw")) ||
+		bytes.HasPrefix(line, []byte("replacing crashed worker "))
+}
+
+func (obs *pytestObserver) observe(line string, now time.Time) {
+	// The crash lines are rare: check them cheaply before matching.
+	if strings.Contains(line, "] node down: ") {
+		if m := xdistDownRe.FindStringSubmatch(line); m != nil {
+			worker := m[xdistDownRe.SubexpIndex("gw")]
+			obs.down[worker] = true
+			obs.records.crash(worker, m[xdistDownRe.SubexpIndex("reason")])
+			obs.suspect(worker, now)
+			return
 		}
+	}
+	if strings.HasPrefix(line, "replacing crashed worker ") {
+		if m := xdistReplaceRe.FindStringSubmatch(line); m != nil {
+			worker := m[xdistReplaceRe.SubexpIndex("gw")]
+			delete(obs.down, worker)
+			obs.landSuspect(worker)
+			return
+		}
+	}
 
-		// This match is present both for started and landed lines.
-		name := groups["name"]
+	// The indexes of the submatches, to avoid allocating them as strings.
+	m := pytestRe.FindStringSubmatchIndex(line)
+	if m == nil {
+		return
+	}
 
-		if groups["status"] == "" {
-			// Started.
-			records.mu.Lock()
-			records.flying[name] = event{name: name, started: now}
-			records.mu.Unlock()
-		} else {
-			// Finished.
-			records.mu.Lock()
-			evt := records.flying[name]
-			evt.finished = now
-			delete(records.flying, name)
-			records.landed[name] = evt
-			records.mu.Unlock()
+	// This match is present both for started and landed lines.
+	name := submatch(line, m, pytestNameIdx)
+	status := submatch(line, m, pytestStatusIdx)
+
+	if status == "" {
+		obs.records.takeoff(name, now, pytestPath(name)...)
+		obs.assign(name)
+		return
+	}
+	// With pytest-rerunfailures, a test that fails lands with status RERUN
+	// and takes off again, as a new attempt.
+	worker := strings.Trim(submatch(line, m, pytestGwIdx), "[]")
+	obs.unassign(name)
+	if obs.down[worker] && status == "FAILED" {
+		delete(obs.suspects, worker)
+		obs.records.landOnCrashed(worker, name, now, pytestPath(name)...)
+		return
+	}
+	obs.records.landOn(worker, name, status, now, 0, pytestPath(name)...)
+	if !slices.Contains(obs.idle, worker) {
+		obs.idle = append(obs.idle, worker)
+	}
+}
+
+// assign assigns test name, which just took off, to the first idle worker.
+// This is a heuristic: pytest-xdist does not say on which worker a test takes
+// off, but a worker runs a test at a time and takes the next one as soon as it
+// lands the previous one.
+func (obs *pytestObserver) assign(name string) {
+	if len(obs.idle) == 0 {
+		obs.unassigned = append(obs.unassigned, name)
+		return
+	}
+	obs.current[obs.idle[0]] = name
+	obs.idle = obs.idle[1:]
+}
+
+// unassign forgets test name, which just landed.
+func (obs *pytestObserver) unassign(name string) {
+	for worker, cur := range obs.current {
+		if cur == name {
+			delete(obs.current, worker)
 		}
 	}
-	if err := scanOut.Err(); err != nil {
-		// In this case, we only print the error and keep going.
-		out("timeit: reading from stdout: %s\n", err)
+	if i := slices.Index(obs.unassigned, name); i >= 0 {
+		obs.unassigned = slices.Delete(obs.unassigned, i, i+1)
 	}
+	for worker, sus := range obs.suspects {
+		if sus.name == name {
+			delete(obs.suspects, worker)
+		}
+	}
+}
 
-	// FIXME WHAT IS THIS FOR???
-	// done <- struct{}{}
-	// close(done)
+// suspect records the test that worker was running when it went down, at now.
+// If the test is not known, it is the oldest unassigned one.
+func (obs *pytestObserver) suspect(worker string, now time.Time) {
+	name, ok := obs.current[worker]
+	if !ok {
+		if len(obs.unassigned) == 0 {
+			return
+		}
+		name = obs.unassigned[0]
+	}
+	obs.suspects[worker] = pytestSuspect{name: name, at: now}
+}
+
+// landSuspect lands with status CRASHED the suspect of worker, if any.
+func (obs *pytestObserver) landSuspect(worker string) {
+	sus, ok := obs.suspects[worker]
+	if !ok {
+		return
+	}
+	delete(obs.suspects, worker)
+	obs.unassign(sus.name)
+	obs.records.landOnCrashed(worker, sus.name, sus.at, pytestPath(sus.name)...)
+}
+
+// finish lands the suspects of the workers that were not replaced.
+func (obs *pytestObserver) finish(t0 time.Time) error {
+	for _, worker := range slices.Sorted(maps.Keys(obs.suspects)) {
+		obs.landSuspect(worker)
+	}
+	return nil
+}
+
+// pytestPath returns the path of a pytest node ID, for example
+// "test_fruits.py::TestApple::test_color[red::green]" returns
+// "test_fruits.py", "TestApple", "test_color[red::green]".
+func pytestPath(name string) []string {
+	// The parameters of a parametrized test can contain "::".
+	name, params, _ := strings.Cut(strings.TrimSpace(name), "[")
+	path := strings.Split(name, "::")
+	if params != "" {
+		path[len(path)-1] += "[" + params
+	}
+	return path
 }
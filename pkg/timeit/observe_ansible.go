@@ -0,0 +1,104 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Lines printed by ansible-playbook:
+//
+//	PLAY [webservers] **************************************************************
+//
+//	TASK [common : install packages] ***********************************************
+//	changed: [web1]
+//	ok: [web2]
+//	fatal: [web3]: FAILED! => {"changed": false, "msg": "No package matching 'foo'"}
+//	fatal: [web4]: UNREACHABLE! => {"changed": false, "unreachable": true}
+//
+//	RUNNING HANDLER [nginx : restart] **********************************************
+//	changed: [web1]
+//
+//	PLAY RECAP *********************************************************************
+//
+// Ansible prints only the start of a task, so a task ends when the next one (or the
+// next play, or the recap) starts. The status of a task counts the outcome of each
+// host; with loops, the outcome of a host is the worst one of its items.
+var (
+	ansibleHeaderRe = regexp.MustCompile(
+		`^(?P<kind>PLAY RECAP|PLAY|TASK|RUNNING HANDLER)(?: \[.*\])? \*+$`)
+	ansibleHostRe = regexp.MustCompile(
+		`^(?P<outcome>ok|changed|skipping|failed|fatal): \[(?P<host>[^\]]+)\](?P<rest>.*)$`)
+)
+
+// Host outcomes, from the best to the worst.
+var ansibleOutcomes = []string{"ok", "changed", "skipped", "failed", "unreachable"}
+
+type ansibleObserver struct {
+	records *records
+	// Name of the task in flight, if any.
+	task string
+	// Host -> index in ansibleOutcomes, for the task in flight.
+	hosts map[string]int
+}
+
+func newAnsibleObserver(records *records, cfg config) observer {
+	return &ansibleObserver{records: records, hosts: make(map[string]int, 50)}
+}
+
+func (obs *ansibleObserver) observe(line string, now time.Time) {
+	if m := ansibleHeaderRe.FindStringSubmatch(line); m != nil {
+		obs.landTask(now)
+		switch m[ansibleHeaderRe.SubexpIndex("kind")] {
+		case "TASK", "RUNNING HANDLER":
+			obs.task = strings.TrimRight(line, " *")
+			obs.records.takeoff(obs.task, now)
+		}
+		return
+	}
+
+	m := ansibleHostRe.FindStringSubmatch(line)
+	if m == nil || obs.task == "" {
+		return
+	}
+	var outcome string
+	switch outcome = m[ansibleHostRe.SubexpIndex("outcome")]; outcome {
+	case "skipping":
+		outcome = "skipped"
+	case "fatal":
+		outcome = "failed"
+		if strings.Contains(m[ansibleHostRe.SubexpIndex("rest")], "UNREACHABLE!") {
+			outcome = "unreachable"
+		}
+	}
+	idx := slices.Index(ansibleOutcomes, outcome)
+	host := m[ansibleHostRe.SubexpIndex("host")]
+	if prev, ok := obs.hosts[host]; !ok || idx > prev {
+		obs.hosts[host] = idx
+	}
+}
+
+// landTask lands the task in flight, if any.
+func (obs *ansibleObserver) landTask(now time.Time) {
+	if obs.task == "" {
+		return
+	}
+	counts := make([]int, len(ansibleOutcomes))
+	for _, idx := range obs.hosts {
+		counts[idx]++
+	}
+	var status []string
+	for idx, count := range counts {
+		if count > 0 {
+			status = append(status, fmt.Sprintf("%s=%d", ansibleOutcomes[idx], count))
+		}
+	}
+	obs.records.land(obs.task, strings.Join(status, " "), now, 0)
+	obs.task = ""
+	clear(obs.hosts)
+}
@@ -0,0 +1,43 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+func TestAnsibleObserver(t *testing.T) {
+	t0 := time.Now()
+	records := feed(t0, newAnsibleObserver, config{}, []line{
+		{0, "PLAY [webservers] **************************************************"},
+		{0, ""},
+		{0, "TASK [Gathering Facts] *********************************************"},
+		{2 * time.Second, "ok: [web1]"},
+		{2 * time.Second, "ok: [web2]"},
+		{3 * time.Second, "TASK [common : install packages] ***************************"},
+		{4 * time.Second, "changed: [web1] => (item=nginx)"},
+		{4 * time.Second, "ok: [web1] => (item=curl)"},
+		{5 * time.Second, `fatal: [web2]: FAILED! => {"changed": false, "msg": "No package"}`},
+		{5 * time.Second, `fatal: [web3]: UNREACHABLE! => {"changed": false, "unreachable": true}`},
+		{5 * time.Second, "skipping: [web4]"},
+		{6 * time.Second, "RUNNING HANDLER [nginx : restart] **********************************"},
+		{8 * time.Second, "changed: [web1]"},
+		{8 * time.Second, ""},
+		{9 * time.Second, "PLAY RECAP *********************************************************"},
+		{9 * time.Second, "web1 : ok=3 changed=2 unreachable=0 failed=0 skipped=0"},
+		{10 * time.Second, "TASK [after the recap, never landed] *******************************"},
+	})
+
+	assert.Check(t, cmp.DeepEqual(durations(records.landed), map[string]string{
+		"TASK [Gathering Facts]":            "3s ok=2",
+		"TASK [common : install packages]":  "3s changed=1 skipped=1 failed=1 unreachable=1",
+		"RUNNING HANDLER [nginx : restart]": "3s changed=1",
+	}))
+	assert.Check(t, cmp.Len(flying(records), 1))
+	assert.Check(t, cmp.Contains(flying(records), "TASK [after the recap, never landed]"))
+}
@@ -0,0 +1,81 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Lines printed by Docker BuildKit with --progress=plain (on stderr):
+//
+//	#5 [builder 1/4] FROM docker.io/library/golang:1.23
+//	#5 CACHED
+//	#7 [builder 3/4] RUN make
+//	#8 [stage-2 2/3] RUN npm ci
+//	#7 0.312 go build ./...
+//	#8 1.045 added 1200 packages
+//	#7 [builder 3/4] RUN make
+//	#7 DONE 42.3s
+//	#8 ERROR: process "/bin/sh -c npm ci" did not complete successfully: exit code: 1
+//
+// The first line of a step is its header. Step #0 is informational and ignored.
+// When the steps of parallel stages interleave, BuildKit prints again the header
+// of a step before resuming its output; since we key the flights by step number,
+// the repetition is harmless.
+var (
+	buildkitLineRe = regexp.MustCompile(`^#(?P<step>\d+) (?P<rest>.+)$`)
+	buildkitEndRe  = regexp.MustCompile(
+		`^(?P<status>DONE|CACHED|CANCELED|ERROR)(?: (?P<secs>\d+(?:\.\d+)?)s)?(?::.*)?$`)
+	// Lines of output of a step (after the header) start with a timestamp
+	// relative to the start of the step.
+	buildkitOutputRe = regexp.MustCompile(`^\d+\.\d+ `)
+)
+
+type buildkitObserver struct {
+	records *records
+	// Step number -> flight name, for the steps we have seen.
+	steps map[string]string
+}
+
+func newBuildkitObserver(records *records, cfg config) observer {
+	return &buildkitObserver{records: records, steps: make(map[string]string, 100)}
+}
+
+func (obs *buildkitObserver) wants(line []byte) bool {
+	return bytes.HasPrefix(line, []byte("#"))
+}
+
+func (obs *buildkitObserver) observe(line string, now time.Time) {
+	m := buildkitLineRe.FindStringSubmatch(line)
+	if m == nil {
+		return
+	}
+	step := m[buildkitLineRe.SubexpIndex("step")]
+	rest := m[buildkitLineRe.SubexpIndex("rest")]
+	if step == "0" {
+		return
+	}
+
+	if end := buildkitEndRe.FindStringSubmatch(rest); end != nil {
+		name, ok := obs.steps[step]
+		if !ok {
+			name = "#" + step
+		}
+		// The regexp guarantees that this is a number, when present.
+		secs, _ := strconv.ParseFloat(end[buildkitEndRe.SubexpIndex("secs")], 64)
+		obs.records.land(name, end[buildkitEndRe.SubexpIndex("status")], now,
+			time.Duration(secs*float64(time.Second)))
+		return
+	}
+
+	if _, ok := obs.steps[step]; ok || buildkitOutputRe.MatchString(rest) {
+		return
+	}
+	name := "#" + step + " " + rest
+	obs.steps[step] = name
+	obs.records.takeoff(name, now)
+}
@@ -0,0 +1,41 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+func TestBuildkitObserver(t *testing.T) {
+	t0 := time.Now()
+	records := feed(t0, newBuildkitObserver, config{}, []line{
+		{0, `#0 building with "default" instance using docker driver`},
+		{0, `#5 [builder 1/4] FROM docker.io/library/golang:1.23`},
+		{0, `#5 resolve docker.io/library/golang:1.23 0.0s done`},
+		{0, `#5 CACHED`},
+		{1 * time.Second, `#7 [builder 3/4] RUN make`},
+		{2 * time.Second, `#8 [stage-2 2/3] RUN npm ci`},
+		{3 * time.Second, `#7 0.312 go build ./...`},
+		{4 * time.Second, `#8 1.045 added 1200 packages`},
+		{5 * time.Second, `#7 [builder 3/4] RUN make`},
+		{6 * time.Second, `#7 DONE 5.1s`},
+		{7 * time.Second, `#8 ERROR: process "/bin/sh -c npm ci" did not complete successfully: exit code: 1`},
+		{8 * time.Second, `#9 DONE 0.5s`},
+		{9 * time.Second, `#10 exporting to image`},
+		{9 * time.Second, `#10 exporting layers 0.3s done`},
+	})
+
+	assert.Check(t, cmp.DeepEqual(durations(records.landed), map[string]string{
+		"#5 [builder 1/4] FROM docker.io/library/golang:1.23": "0s CACHED",
+		"#7 [builder 3/4] RUN make":                           "5s DONE",
+		"#8 [stage-2 2/3] RUN npm ci":                         "5s ERROR",
+		"#9":                                                  "500ms DONE",
+	}))
+	assert.Check(t, cmp.Len(flying(records), 1))
+	assert.Check(t, cmp.Contains(flying(records), "#10 exporting to image"))
+}
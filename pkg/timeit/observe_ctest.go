@@ -0,0 +1,58 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Lines printed by ctest, also when running with -j:
+//
+//	      Start  12: test_name
+//	12/40 Test #12: test_name ......................   Passed    3.21 sec
+//	13/40 Test #13: other_name .....................***Failed    0.02 sec
+//	14/40 Test #14: third_name .....................***Exception: SegFault  0.01 sec
+//	15/40 Test #15: name with spaces ...............   Passed    0.10 sec
+var (
+	ctestStartRe  = regexp.MustCompile(`^\s*Start\s+\d+: (?P<name>.+?)\s*$`)
+	ctestResultRe = regexp.MustCompile(
+		`^\s*(?P<done>\d+)/(?P<total>\d+) Test\s+#\d+: (?P<name>.+?)\s+\.*\s*\**` +
+			`(?P<status>[A-Za-z][A-Za-z :]*?)\s+(?P<secs>\d+(?:\.\d+)?) sec\s*$`)
+)
+
+type ctestObserver struct {
+	records *records
+}
+
+func newCtestObserver(records *records, cfg config) observer {
+	return &ctestObserver{records: records}
+}
+
+func (obs *ctestObserver) wants(line []byte) bool {
+	return bytes.Contains(line, []byte("Start")) || bytes.Contains(line, []byte("Test"))
+}
+
+func (obs *ctestObserver) observe(line string, now time.Time) {
+	if m := ctestStartRe.FindStringSubmatch(line); m != nil {
+		obs.records.takeoff(m[ctestStartRe.SubexpIndex("name")], now)
+		return
+	}
+
+	m := ctestResultRe.FindStringSubmatch(line)
+	if m == nil {
+		return
+	}
+	// The regexp guarantees that these are numbers.
+	done, _ := strconv.Atoi(m[ctestResultRe.SubexpIndex("done")])
+	total, _ := strconv.Atoi(m[ctestResultRe.SubexpIndex("total")])
+	secs, _ := strconv.ParseFloat(m[ctestResultRe.SubexpIndex("secs")], 64)
+
+	obs.records.land(m[ctestResultRe.SubexpIndex("name")],
+		m[ctestResultRe.SubexpIndex("status")], now,
+		time.Duration(secs*float64(time.Second)))
+	obs.records.setProgress(done, total)
+}
@@ -0,0 +1,39 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+func TestCtestObserver(t *testing.T) {
+	t0 := time.Now()
+	records := feed(t0, newCtestObserver, config{}, []line{
+		{0, "Test project /home/user/build"},
+		{0, "      Start  1: test_a"},
+		{0, "      Start  2: test_b"},
+		{0, "      Start 12: test_c"},
+		{0, "      Start 13: my test"},
+		{1 * time.Second, " 2/40 Test  #2: test_b ...........................   Passed    0.98 sec"},
+		{2 * time.Second, " 1/40 Test  #1: test_a ...........................***Failed    2.01 sec"},
+		{3 * time.Second, " 3/40 Test  #7: test_unseen ......................***Exception: SegFault  0.50 sec"},
+		{3 * time.Second, " 4/40 Test #13: my test ..........................   Passed    0.25 sec"},
+		{4 * time.Second, "100% tests passed, 0 tests failed out of 40"},
+	})
+
+	assert.Check(t, cmp.DeepEqual(durations(records.landed), map[string]string{
+		"test_a":      "2s Failed",
+		"test_b":      "1s Passed",
+		"test_unseen": "500ms Exception: SegFault",
+		"my test":     "3s Passed",
+	}))
+	assert.Check(t, cmp.Len(flying(records), 1))
+	assert.Check(t, cmp.Contains(flying(records), "test_c"))
+	assert.Equal(t, records.done, 4)
+	assert.Equal(t, records.total, 40)
+}
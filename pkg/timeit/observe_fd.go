@@ -0,0 +1,98 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timing records written by the command to the file descriptor in TIMEIT_FD,
+// one per line:
+//
+//	begin NAME
+//	end NAME [STATUS]
+//	mark NAME
+//	progress DONE/TOTAL
+//
+// NAME and STATUS are either a single word or a Go (double-quoted) string. A NAME
+// containing sectionSep is nested: its path is the split of NAME. For example:
+//
+//	begin "unit tests"
+//	begin "unit tests > parser"
+//	end "unit tests > parser" ok
+//	end "unit tests" ok
+//
+// Lines that are not valid records are ignored.
+type fdObserver struct {
+	records *records
+	t0      time.Time
+}
+
+func newFDObserver(records *records, t0 time.Time) observer {
+	return &fdObserver{records: records, t0: t0}
+}
+
+func (obs *fdObserver) observe(line string, now time.Time) {
+	fields, ok := splitQuoted(line)
+	if !ok || len(fields) < 2 {
+		return
+	}
+	switch kind, name := fields[0], fields[1]; {
+	case kind == "begin" && len(fields) == 2:
+		obs.records.takeoff(name, now, fdPath(name)...)
+	case kind == "end" && len(fields) <= 3:
+		var status string
+		if len(fields) == 3 {
+			status = fields[2]
+		}
+		obs.records.land(name, status, now, 0, fdPath(name)...)
+	case kind == "mark" && len(fields) == 2:
+		obs.records.addMark(name, now.Sub(obs.t0))
+	case kind == "progress" && len(fields) == 2:
+		doneStr, totalStr, found := strings.Cut(name, "/")
+		done, err1 := strconv.Atoi(doneStr)
+		total, err2 := strconv.Atoi(totalStr)
+		if found && err1 == nil && err2 == nil && done >= 0 && total > 0 {
+			obs.records.setProgress(done, total)
+		}
+	}
+}
+
+// fdPath returns the path of name, or nil if name is not nested.
+func fdPath(name string) []string {
+	if !strings.Contains(name, sectionSep) {
+		return nil
+	}
+	return strings.Split(name, sectionSep)
+}
+
+// splitQuoted splits line around spaces, where a field that starts with a double
+// quote is a Go string literal. It returns false if a string literal is invalid.
+func splitQuoted(line string) ([]string, bool) {
+	var fields []string
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			return fields, true
+		}
+		if line[0] == '"' {
+			quoted, err := strconv.QuotedPrefix(line)
+			if err != nil {
+				return nil, false
+			}
+			field, _ := strconv.Unquote(quoted)
+			fields = append(fields, field)
+			line = line[len(quoted):]
+			continue
+		}
+		end := strings.IndexAny(line, " \t")
+		if end == -1 {
+			end = len(line)
+		}
+		fields = append(fields, line[:end])
+		line = line[end:]
+	}
+}
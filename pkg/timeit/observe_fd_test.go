@@ -0,0 +1,45 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+func TestFDObserver(t *testing.T) {
+	t0 := time.Now()
+	records := newRecords()
+	obs := newFDObserver(records, t0)
+	for _, l := range []line{
+		{0, "begin build"},
+		{0, `begin "unit tests"`},
+		{0, "begin never-ends"},
+		{1 * time.Second, "mark compiled"},
+		{2 * time.Second, "end build"},
+		{3 * time.Second, "progress 45/100"},
+		{3 * time.Second, "progress 45/0"},
+		{3 * time.Second, "progress nonsense"},
+		{4 * time.Second, `end "unit tests" "2 failed"`},
+		{4 * time.Second, `end "unterminated`},
+		{4 * time.Second, "begin too many fields"},
+		{4 * time.Second, "unknown record"},
+	} {
+		obs.observe(l.text, t0.Add(l.at))
+	}
+
+	assert.Check(t, cmp.DeepEqual(durations(records.landed), map[string]string{
+		"build":      "2s ",
+		"unit tests": "4s 2 failed",
+	}))
+	assert.Check(t, cmp.Len(flying(records), 1))
+	assert.Check(t, cmp.Contains(flying(records), "never-ends"))
+	assert.Check(t, cmp.Len(records.marks, 1))
+	assert.Check(t, records.marks[0] == mark{name: "compiled", at: time.Second})
+	assert.Equal(t, records.done, 45)
+	assert.Equal(t, records.total, 100)
+}
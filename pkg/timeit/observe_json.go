@@ -0,0 +1,99 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Lines of a JSON-lines (structured) log, with the default field mapping:
+//
+//	{"event":"start","name":"fetch","level":"info"}
+//	{"event":"end","name":"fetch","status":"ok","level":"info"}
+//
+// The mapping is configurable with the --json-* flags, for example:
+//
+//	{"event":"task_start","task":{"id":"x"}}
+//	{"event":"task_end","task":{"id":"x"},"result":"failed"}
+//
+// is observed with:
+//
+//	--json-begin=task_start --json-end=task_end --json-name=task.id --json-status=result
+//
+// Lines that are not JSON objects, or that do not have the expected fields, are
+// ignored.
+type jsonObserver struct {
+	records *records
+	begin   string
+	end     string
+	event   []string
+	name    []string
+	status  []string
+}
+
+func newJSONObserver(records *records, cfg config) observer {
+	return &jsonObserver{
+		records: records,
+		begin:   cfg.JSON.Begin,
+		end:     cfg.JSON.End,
+		event:   strings.Split(cfg.JSON.Event, "."),
+		name:    strings.Split(cfg.JSON.Name, "."),
+		status:  strings.Split(cfg.JSON.Status, "."),
+	}
+}
+
+func (obs *jsonObserver) wants(line []byte) bool {
+	return bytes.Contains(line, []byte("{"))
+}
+
+func (obs *jsonObserver) observe(line string, now time.Time) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "{") {
+		return
+	}
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return
+	}
+
+	name, ok := lookupJSON(fields, obs.name)
+	if !ok {
+		return
+	}
+	event, _ := lookupJSON(fields, obs.event)
+	switch event {
+	case obs.begin:
+		obs.records.takeoff(name, now)
+	case obs.end:
+		status, _ := lookupJSON(fields, obs.status)
+		obs.records.land(name, status, now, 0)
+	}
+}
+
+// lookupJSON returns the value at path in fields, formatted as a string.
+// It returns false if path does not exist or does not lead to a scalar.
+func lookupJSON(fields map[string]any, path []string) (string, bool) {
+	var val any = fields
+	for _, key := range path {
+		obj, ok := val.(map[string]any)
+		if !ok {
+			return "", false
+		}
+		if val, ok = obj[key]; !ok {
+			return "", false
+		}
+	}
+	switch val := val.(type) {
+	case string:
+		return val, true
+	case float64, bool:
+		return fmt.Sprint(val), true
+	default:
+		return "", false
+	}
+}
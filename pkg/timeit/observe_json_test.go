@@ -0,0 +1,53 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+func TestJSONObserverDefaultMapping(t *testing.T) {
+	cfg := config{JSON: JSONOptions{
+		Event: "event", Begin: "start", End: "end", Name: "name", Status: "status",
+	}}
+	t0 := time.Now()
+	records := feed(t0, newJSONObserver, cfg, []line{
+		{0, `{"event":"start","name":"fetch"}`},
+		{0, `{"event":"start","name":"build"}`},
+		{0, `not JSON`},
+		{0, `{"event":"start"`},
+		{1 * time.Second, `{"event":"progress","name":"fetch"}`},
+		{2 * time.Second, `{"event":"end","name":"fetch","status":"ok"}`},
+	})
+
+	assert.Check(t, cmp.DeepEqual(durations(records.landed), map[string]string{
+		"fetch": "2s ok",
+	}))
+	assert.Check(t, cmp.Len(flying(records), 1))
+	assert.Check(t, cmp.Contains(flying(records), "build"))
+}
+
+func TestJSONObserverCustomMapping(t *testing.T) {
+	cfg := config{JSON: JSONOptions{
+		Event: "ev.type", Begin: "task_start", End: "task_end", Name: "task.id",
+		Status: "result.code",
+	}}
+	t0 := time.Now()
+	records := feed(t0, newJSONObserver, cfg, []line{
+		{0, `{"ev":{"type":"task_start"},"task":{"id":42}}`},
+		{0, `  {"ev":{"type":"task_start"},"task":{"id":"x"}}`},
+		{1 * time.Second, `{"ev":{"type":"task_end"},"task":{"id":42},"result":{"code":3}}`},
+		{2 * time.Second, `{"ev":{"type":"task_end"},"task":{"id":"x"}}`},
+	})
+
+	assert.Check(t, cmp.DeepEqual(durations(records.landed), map[string]string{
+		"42": "1s 3",
+		"x":  "2s ",
+	}))
+	assert.Check(t, cmp.Len(flying(records), 0))
+}
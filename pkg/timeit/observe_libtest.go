@@ -0,0 +1,86 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Lines printed by the Rust libtest harness (cargo test), also for doc-tests:
+//
+//	running 3 tests
+//	test parser::tests::empty ... ok
+//	test parser::tests::garbage - should panic ... ok
+//	test net::tests::slow has been running for over 60 seconds
+//	test net::tests::offline ... ignored, requires network
+//	test src/lib.rs - parse (line 10) ... FAILED
+//
+// When running tests in parallel (the default), libtest prints only when a test
+// terminates, so the best start time we know is the one of the whole group, from
+// the "running N tests" line. A warning about a slow test tells us that the test
+// is in flight since at least the given amount of time.
+var (
+	libtestRunningRe = regexp.MustCompile(`^running (?P<count>\d+) tests?$`)
+	libtestResultRe  = regexp.MustCompile(
+		`^test (?P<name>.+?)(?: - should panic)? \.\.\. (?P<status>ok|FAILED|ignored)\b`)
+	libtestSlowRe = regexp.MustCompile(
+		`^test (?P<name>.+?) has been running for over (?P<secs>\d+) seconds$`)
+)
+
+type libtestObserver struct {
+	records *records
+	// Start of the current group of tests (one per test binary).
+	groupStart time.Time
+	done       int
+	total      int
+}
+
+func newLibtestObserver(records *records, cfg config) observer {
+	return &libtestObserver{records: records}
+}
+
+func (obs *libtestObserver) wants(line []byte) bool {
+	return bytes.HasPrefix(line, []byte("test ")) || bytes.HasPrefix(line, []byte("running "))
+}
+
+func (obs *libtestObserver) observe(line string, now time.Time) {
+	if m := libtestRunningRe.FindStringSubmatch(line); m != nil {
+		// The regexp guarantees that this is a number.
+		count, _ := strconv.Atoi(m[libtestRunningRe.SubexpIndex("count")])
+		obs.groupStart = now
+		obs.total += count
+		obs.records.setProgress(obs.done, obs.total)
+		return
+	}
+
+	if m := libtestResultRe.FindStringSubmatch(line); m != nil {
+		obs.done++
+		obs.records.land(m[libtestResultRe.SubexpIndex("name")],
+			m[libtestResultRe.SubexpIndex("status")], now, obs.sinceGroupStart(now))
+		obs.records.setProgress(obs.done, obs.total)
+		return
+	}
+
+	if m := libtestSlowRe.FindStringSubmatch(line); m != nil {
+		// The regexp guarantees that this is a number.
+		secs, _ := strconv.Atoi(m[libtestSlowRe.SubexpIndex("secs")])
+		started := now.Add(-time.Duration(secs) * time.Second)
+		if started.Before(obs.groupStart) {
+			started = obs.groupStart
+		}
+		obs.records.takeoff(m[libtestSlowRe.SubexpIndex("name")], started)
+	}
+}
+
+// sinceGroupStart returns the time elapsed since the start of the current group of
+// tests, or zero if we never saw the start of a group.
+func (obs *libtestObserver) sinceGroupStart(now time.Time) time.Duration {
+	if obs.groupStart.IsZero() {
+		return 0
+	}
+	return now.Sub(obs.groupStart)
+}
@@ -0,0 +1,39 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+func TestLibtestObserver(t *testing.T) {
+	t0 := time.Now()
+	records := feed(t0, newLibtestObserver, config{}, []line{
+		{0, "     Running unittests src/lib.rs (target/debug/deps/fruits-1234)"},
+		{0, ""},
+		{0, "running 4 tests"},
+		{1 * time.Second, "test parser::tests::empty ... ok"},
+		{2 * time.Second, "test parser::tests::garbage - should panic ... ok"},
+		{3 * time.Second, "test net::tests::offline ... ignored, requires network"},
+		{61 * time.Second, "test net::tests::slow has been running for over 60 seconds"},
+		{70 * time.Second, "test result: FAILED. 3 passed; 1 failed; 1 ignored"},
+		{70 * time.Second, "running 1 test"},
+		{72 * time.Second, "test src/lib.rs - parse (line 10) ... FAILED"},
+	})
+
+	assert.Check(t, cmp.DeepEqual(durations(records.landed), map[string]string{
+		"parser::tests::empty":         "1s ok",
+		"parser::tests::garbage":       "2s ok",
+		"net::tests::offline":          "3s ignored",
+		"src/lib.rs - parse (line 10)": "2s FAILED",
+	}))
+	assert.Check(t, cmp.Len(flying(records), 1))
+	assert.Equal(t, flying(records)["net::tests::slow"].started, t0.Add(time.Second))
+	assert.Equal(t, records.done, 4)
+	assert.Equal(t, records.total, 5)
+}
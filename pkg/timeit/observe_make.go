@@ -0,0 +1,66 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"bytes"
+	"regexp"
+	"time"
+)
+
+// Lines printed by GNU make with --debug=basic (or -d), also together with -j and
+// --output-sync:
+//
+//	Must remake target 'obj/foo.o'.
+//	Successfully remade target file 'obj/foo.o'.
+//	make[1]: *** [Makefile:12: obj/bar.o] Error 1
+//
+// Versions of make before 4.3 quote as `obj/foo.o'. For a failed recipe, make
+// prints the error line and then (with debug enabled) "Failed to remake target
+// file"; we land the flight on the first of the two.
+var (
+	makeStartRe = regexp.MustCompile("^Must remake target [`'](?P<name>.+)'\\.$")
+	makeEndRe   = regexp.MustCompile(
+		"^(?P<status>Successfully|Failed to) remade target file [`'](?P<name>.+)'\\.$")
+	makeErrorRe = regexp.MustCompile(
+		`^g?make(?:\[\d+\])?: \*\*\* \[(?:[^\]]+:\d+: )?(?P<name>[^\]]+)\] (?P<status>Error \d+)`)
+)
+
+type makeObserver struct {
+	records *records
+	// Targets landed by an error line.
+	failed map[string]bool
+}
+
+func newMakeObserver(records *records, cfg config) observer {
+	return &makeObserver{records: records, failed: make(map[string]bool)}
+}
+
+func (obs *makeObserver) wants(line []byte) bool {
+	return bytes.Contains(line, []byte(" target ")) || bytes.Contains(line, []byte("***"))
+}
+
+func (obs *makeObserver) observe(line string, now time.Time) {
+	if m := makeStartRe.FindStringSubmatch(line); m != nil {
+		obs.records.takeoff(m[makeStartRe.SubexpIndex("name")], now)
+		return
+	}
+	if m := makeEndRe.FindStringSubmatch(line); m != nil {
+		name := m[makeEndRe.SubexpIndex("name")]
+		if obs.failed[name] {
+			return
+		}
+		status := "ok"
+		if m[makeEndRe.SubexpIndex("status")] != "Successfully" {
+			status = "failed"
+		}
+		obs.records.land(name, status, now, 0)
+		return
+	}
+	if m := makeErrorRe.FindStringSubmatch(line); m != nil {
+		name := m[makeErrorRe.SubexpIndex("name")]
+		obs.failed[name] = true
+		obs.records.land(name, m[makeErrorRe.SubexpIndex("status")], now, 0)
+	}
+}
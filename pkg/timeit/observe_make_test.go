@@ -0,0 +1,35 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+func TestMakeObserver(t *testing.T) {
+	t0 := time.Now()
+	records := feed(t0, newMakeObserver, config{}, []line{
+		{0, "GNU Make 4.4.1"},
+		{0, "Must remake target 'obj/foo.o'."},
+		{0, "Must remake target `obj/bar.o'."},
+		{0, "Must remake target 'obj/baz.o'."},
+		{0, "cc -c -o obj/foo.o foo.c"},
+		{2 * time.Second, "Successfully remade target file 'obj/foo.o'."},
+		{3 * time.Second, "make: *** [Makefile:12: obj/bar.o] Error 1"},
+		{3 * time.Second, "Failed to remake target file `obj/bar.o'."},
+		{4 * time.Second, "make[2]: *** [obj/qux.o] Error 2"},
+	})
+
+	assert.Check(t, cmp.DeepEqual(durations(records.landed), map[string]string{
+		"obj/foo.o": "2s ok",
+		"obj/bar.o": "3s Error 1",
+		"obj/qux.o": "0s Error 2",
+	}))
+	assert.Check(t, cmp.Len(flying(records), 1))
+	assert.Check(t, cmp.Contains(flying(records), "obj/baz.o"))
+}
@@ -0,0 +1,190 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Lines printed by ninja (with the default NINJA_STATUS="[%f/%t] "):
+//
+//	[1/4567] CXX obj/foo.o
+//	[2/4567] CXX obj/bar.o
+//	FAILED: obj/bar.o
+//	[3/4567] LINK bin/app
+//
+// When its output is not a terminal, ninja prints the status line of an edge
+// only when the edge terminates, so the duration of an edge is approximated by the
+// time since the previous status line (or since the start, for the first edge).
+// Once the build terminates, the durations of the edges are replaced with the real
+// ones from the ninja log, if given and it exists (ninja does not log the edges
+// that failed).
+// The edges are then named by their output, as in the log, instead of by their
+// description.
+var (
+	ninjaStatusRe = regexp.MustCompile(
+		`^\[(?P<done>\d+)/(?P<total>\d+)\] (?P<name>.+)$`)
+	// Newer versions of ninja print also the exit code: "FAILED: [code=1] obj/bar.o".
+	ninjaFailedRe = regexp.MustCompile(`^FAILED: (?:\[code=\d+\] )?(?P<output>\S+)`)
+)
+
+type ninjaObserver struct {
+	records *records
+	logPath string
+	// When the previous edge terminated, or when the build started.
+	last time.Time
+	// The edge that terminated last, to which a FAILED line refers.
+	lastName string
+	lastDur  time.Duration
+	// The failed edges, named by their output, since ninja does not log them.
+	failed []event
+	done   int
+}
+
+func newNinjaObserver(records *records, cfg config) observer {
+	return &ninjaObserver{records: records, logPath: cfg.NinjaLog}
+}
+
+func (obs *ninjaObserver) wants(line []byte) bool {
+	return bytes.HasPrefix(line, []byte("[")) || bytes.HasPrefix(line, []byte("FAILED: "))
+}
+
+func (obs *ninjaObserver) start(t0 time.Time) {
+	obs.last = t0
+}
+
+func (obs *ninjaObserver) observe(line string, now time.Time) {
+	if m := ninjaFailedRe.FindStringSubmatch(line); m != nil && obs.lastName != "" {
+		obs.records.amend(obs.lastName, "FAILED", 0)
+		obs.failed = append(obs.failed, event{
+			name:     m[ninjaFailedRe.SubexpIndex("output")],
+			status:   "FAILED",
+			started:  obs.last.Add(-obs.lastDur),
+			finished: obs.last,
+		})
+		return
+	}
+
+	m := ninjaStatusRe.FindStringSubmatch(line)
+	if m == nil {
+		return
+	}
+	// The regexp guarantees that these are numbers.
+	obs.done, _ = strconv.Atoi(m[ninjaStatusRe.SubexpIndex("done")])
+	total, _ := strconv.Atoi(m[ninjaStatusRe.SubexpIndex("total")])
+
+	obs.lastName = m[ninjaStatusRe.SubexpIndex("name")]
+	obs.lastDur = now.Sub(obs.last)
+	obs.records.land(obs.lastName, "", now, obs.lastDur)
+	obs.records.setProgress(obs.done, total)
+	obs.last = now
+}
+
+// finish replaces the approximated durations of the edges with the ones from the
+// ninja log, if it exists.
+func (obs *ninjaObserver) finish(t0 time.Time) error {
+	// Replaying a recorded build reads the log only if given (see replayConfig).
+	if obs.logPath == "" {
+		return nil
+	}
+	entries, err := readNinjaLog(obs.logPath, obs.done)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	obs.records.forgetLanded()
+	for _, evt := range obs.failed {
+		obs.records.land(evt.name, evt.status, evt.finished, evt.duration())
+	}
+	for _, entry := range entries {
+		obs.records.land(entry.name, "", t0.Add(entry.end), entry.end-entry.start)
+	}
+	return nil
+}
+
+type ninjaLogEntry struct {
+	name  string
+	start time.Duration
+	end   time.Duration
+}
+
+// readNinjaLog returns at most the last maxEntries entries of the ninja log at
+// path that belong to the last build.
+//
+// Each line of the log (format v5 and later) is:
+//
+//	start_ms <TAB> end_ms <TAB> mtime <TAB> output <TAB> command_hash
+//
+// where the times are relative to the start of the build. Ninja appends an entry
+// when an edge terminates, so end_ms is monotonic within a build; the entries of
+// the last build are the ones after the last time end_ms went backwards.
+// An edge with multiple outputs has one entry per output, with the same times and
+// hash; we keep only the first.
+func readNinjaLog(path string, maxEntries int) ([]ninjaLogEntry, error) {
+	fi, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fi.Close()
+
+	var entries []ninjaLogEntry
+	type edgeKey struct{ start, end, hash string }
+	seen := make(map[edgeKey]bool)
+	var prevEnd time.Duration
+	scanner := bufio.NewScanner(fi)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("ninja log %s: malformed line: %q", path, line)
+		}
+		startMs, err1 := strconv.Atoi(fields[0])
+		endMs, err2 := strconv.Atoi(fields[1])
+		if err := errors.Join(err1, err2); err != nil {
+			return nil, fmt.Errorf("ninja log %s: %s", path, err)
+		}
+		end := time.Duration(endMs) * time.Millisecond
+		if end < prevEnd {
+			// A new build started.
+			entries = entries[:0]
+			clear(seen)
+		}
+		prevEnd = end
+		key := edgeKey{fields[0], fields[1], fields[4]}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		entries = append(entries, ninjaLogEntry{
+			name:  fields[3],
+			start: time.Duration(startMs) * time.Millisecond,
+			end:   end,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ninja log %s: %s", path, err)
+	}
+	if len(entries) > maxEntries {
+		entries = entries[len(entries)-maxEntries:]
+	}
+	return entries, nil
+}
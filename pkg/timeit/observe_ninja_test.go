@@ -0,0 +1,81 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+func TestNinjaObserver(t *testing.T) {
+	cfg := config{NinjaLog: filepath.Join(t.TempDir(), "does-not-exist")}
+	t0 := time.Now()
+	records := feed(t0, newNinjaObserver, cfg, []line{
+		{0, "ninja: Entering directory `build'"},
+		{1 * time.Second, "[1/4] CXX obj/foo.o"},
+		{3 * time.Second, "[2/4] CXX obj/bar.o"},
+		{3 * time.Second, "FAILED: [code=1] obj/bar.o"},
+		{3 * time.Second, "bar.cc:1:1: error: expected unqualified-id"},
+		{4 * time.Second, "ninja: build stopped: subcommand failed."},
+	})
+
+	assert.Check(t, cmp.DeepEqual(durations(records.landed), map[string]string{
+		"CXX obj/foo.o": "1s ",
+		"CXX obj/bar.o": "2s FAILED",
+	}))
+	assert.Equal(t, records.done, 2)
+	assert.Equal(t, records.total, 4)
+}
+
+func TestNinjaObserverFirstEdgeFromStart(t *testing.T) {
+	cfg := config{NinjaLog: filepath.Join(t.TempDir(), "does-not-exist")}
+	t0 := time.Now()
+	// Without "ninja: Entering directory", as with ninja run without -C.
+	records := feed(t0, newNinjaObserver, cfg, []line{
+		{2 * time.Second, "[1/2] CXX obj/foo.o"},
+		{3 * time.Second, "[2/2] LINK bin/app"},
+	})
+
+	assert.Check(t, cmp.DeepEqual(durations(records.landed), map[string]string{
+		"CXX obj/foo.o": "2s ",
+		"LINK bin/app":  "1s ",
+	}))
+}
+
+func TestNinjaObserverFinishReadsLog(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), ".ninja_log")
+	// Two builds: the second one rebuilds obj/foo.o and links. The link edge has
+	// two outputs.
+	log := "# ninja log v5\n" +
+		"0\t1500\t0\tobj/foo.o\taaaa\n" +
+		"0\t1600\t0\tobj/bar.o\tbbbb\n" +
+		"1600\t1900\t0\tbin/app\tcccc\n" +
+		"0\t1000\t0\tobj/foo.o\tdddd\n" +
+		"1000\t1500\t0\tbin/app\teeee\n" +
+		"1000\t1500\t0\tbin/app.map\teeee\n"
+	assert.NilError(t, os.WriteFile(logPath, []byte(log), 0o644))
+
+	t0 := time.Now()
+	records := newRecords()
+	obs := newNinjaObserver(records, config{NinjaLog: logPath})
+	obs.(starter).start(t0.Add(time.Second))
+	obs.observe("[1/3] CXX obj/foo.o", t0.Add(2*time.Second))
+	obs.observe("[2/3] CXX obj/baz.o", t0.Add(2*time.Second))
+	obs.observe("FAILED: obj/baz.o", t0.Add(2*time.Second))
+	obs.observe("[3/3] LINK bin/app", t0.Add(3*time.Second))
+
+	assert.NilError(t, obs.(finisher).finish(t0))
+
+	assert.Check(t, cmp.DeepEqual(durations(records.landed), map[string]string{
+		"obj/foo.o": "1s ",
+		"bin/app":   "500ms ",
+		// Named by its output, as the edges of the log.
+		"obj/baz.o": "0s FAILED",
+	}))
+}
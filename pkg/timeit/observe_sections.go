@@ -0,0 +1,91 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"bytes"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Section markers of CI systems, that delimit the phases of a script:
+//
+//	::group::Build                                  GitHub Actions
+//	::endgroup::
+//	##[group]Build                                  Azure Pipelines
+//	##[endgroup]
+//	##[section]Starting: Build                      Azure Pipelines
+//	##[section]Finishing: Build
+//	section_start:1560896352:build\r\e[0KBuild      GitLab CI
+//	section_end:1560896353:build\r\e[0K
+//
+// Sections can be nested; the name of a nested flight is its path, separated by
+// sectionSep. A marker that closes a section by name closes also the sections
+// nested in it that are still open.
+var (
+	sectionsStartRe = regexp.MustCompile(
+		`^(?:::group::|##\[group\]|##\[section\]Starting: )(?P<name>.+?)\s*$` +
+			`|section_start:\d+:(?P<id>[^\[\r\s]+)`)
+	sectionsEndRe = regexp.MustCompile(
+		`^(?:::endgroup::|##\[endgroup\])` +
+			`|^##\[section\]Finishing: (?P<name>.+?)\s*$` +
+			`|section_end:\d+:(?P<id>[^\[\r\s]+)`)
+)
+
+const sectionSep = " > "
+
+type sectionsObserver struct {
+	records *records
+	// The open sections, innermost last.
+	stack []string
+}
+
+func newSectionsObserver(records *records, cfg config) observer {
+	return &sectionsObserver{records: records}
+}
+
+func (obs *sectionsObserver) wants(line []byte) bool {
+	return bytes.Contains(line, []byte("::")) || bytes.Contains(line, []byte("##[")) ||
+		bytes.Contains(line, []byte("section_"))
+}
+
+func (obs *sectionsObserver) observe(line string, now time.Time) {
+	if m := sectionsStartRe.FindStringSubmatch(line); m != nil {
+		name := m[sectionsStartRe.SubexpIndex("name")] + m[sectionsStartRe.SubexpIndex("id")]
+		obs.stack = append(obs.stack, name)
+		obs.records.takeoff(strings.Join(obs.stack, sectionSep), now,
+			slices.Clone(obs.stack)...)
+		return
+	}
+
+	m := sectionsEndRe.FindStringSubmatch(line)
+	if m == nil || len(obs.stack) == 0 {
+		return
+	}
+	// Without a name, close the innermost section.
+	depth := len(obs.stack) - 1
+	if name := m[sectionsEndRe.SubexpIndex("name")] + m[sectionsEndRe.SubexpIndex("id")]; name != "" {
+		depth = lastIndexOf(obs.stack, name)
+		if depth == -1 {
+			return
+		}
+	}
+	for len(obs.stack) > depth {
+		obs.records.land(strings.Join(obs.stack, sectionSep), "", now, 0)
+		obs.stack = obs.stack[:len(obs.stack)-1]
+	}
+}
+
+// lastIndexOf returns the index of the last occurrence of elem in list, or -1 if
+// not found.
+func lastIndexOf(list []string, elem string) int {
+	for i := len(list) - 1; i >= 0; i-- {
+		if list[i] == elem {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,42 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+func TestSectionsObserver(t *testing.T) {
+	t0 := time.Now()
+	records := feed(t0, newSectionsObserver, config{}, []line{
+		{0, "::group::Build"},
+		{1 * time.Second, "##[group]compile"},
+		{3 * time.Second, "##[endgroup]"},
+		{3 * time.Second, "\x1b[0Ksection_start:1560896352:link[collapsed=true]\r\x1b[0KLinking"},
+		{4 * time.Second, "section_end:1560896353:link\r\x1b[0K"},
+		{4 * time.Second, "::endgroup::"},
+		{4 * time.Second, "##[section]Starting: Test"},
+		{5 * time.Second, "section_start:1560896353:unit\r\x1b[0KUnit tests"},
+		{5 * time.Second, "section_start:1560896353:slow\r\x1b[0KSlow tests"},
+		{7 * time.Second, "##[section]Finishing: Test"},
+		{8 * time.Second, "section_end:1560896360:does-not-exist\r\x1b[0K"},
+		{8 * time.Second, "::group::Deploy"},
+		{9 * time.Second, "section_end:1560896360:does-not-exist\r\x1b[0K"},
+	})
+
+	assert.Check(t, cmp.DeepEqual(durations(records.landed), map[string]string{
+		"Build":              "4s ",
+		"Build > compile":    "2s ",
+		"Build > link":       "1s ",
+		"Test":               "3s ",
+		"Test > unit":        "2s ",
+		"Test > unit > slow": "2s ",
+	}))
+	assert.Check(t, cmp.Len(flying(records), 1))
+	assert.Check(t, cmp.Contains(flying(records), "Deploy"))
+}
@@ -0,0 +1,147 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Lines of a TAP (Test Anything Protocol) stream, versions 13 and 14
+// (https://testanything.org/tap-version-14-specification.html):
+//
+//	TAP version 14
+//	1..4
+//	ok 1 - parse empty input
+//	not ok 2 - parse garbage
+//	  ---
+//	  message: unexpected token
+//	  duration_ms: 12.5
+//	  ...
+//	ok 3 - network # SKIP offline
+//	not ok 4 - unicode # TODO not implemented yet
+//
+// A TAP producer prints only when a test terminates, and runs the tests
+// sequentially, so a test starts when the previous one terminates. Since we do not
+// know the name of the next test, it is in flight as "#N". If the YAML diagnostics
+// block of a test contains duration_ms (as node-tap does), that duration is used.
+//
+// Indented test points belong to subtests and are ignored: the parent test point
+// follows them. So are their YAML blocks, indented 2 spaces more than their test
+// point: only a block at 2 spaces, right after a test point that is not indented,
+// is about a test.
+var (
+	tapPlanRe = regexp.MustCompile(`^1\.\.(?P<count>\d+)`)
+	tapTestRe = regexp.MustCompile(
+		`^(?P<ok>not ok|ok)\b(?:\s+(?P<num>\d+))?(?:\s+-)?\s*(?P<desc>.*?)` +
+			`(?:\s*#\s*(?P<directive>(?i:skip|todo))\b.*)?$`)
+	tapSubtestRe   = regexp.MustCompile(`^\s+(?:not ok|ok)\b`)
+	tapYamlStartRe = regexp.MustCompile(`^  ---\s*$`)
+	tapYamlEndRe   = regexp.MustCompile(`^  \.\.\.\s*$`)
+	tapDurationRe  = regexp.MustCompile(`^  duration_ms:\s*(?P<ms>\d+(?:\.\d+)?)\s*$`)
+)
+
+type tapObserver struct {
+	records *records
+	// When the previous test terminated, or when the stream started.
+	last time.Time
+	// Number of the next test, from the point of view of the stream.
+	next  int
+	done  int
+	total int
+	// The test that terminated last, to which a YAML block refers, if no subtest
+	// test point followed it.
+	landed tapTest
+	inYaml bool
+}
+
+type tapTest struct {
+	name     string
+	status   string
+	finished time.Time
+}
+
+func newTapObserver(records *records, cfg config) observer {
+	return &tapObserver{records: records, next: 1}
+}
+
+func (obs *tapObserver) observe(line string, now time.Time) {
+	if obs.last.IsZero() {
+		obs.last = now
+	}
+
+	if obs.inYaml {
+		if tapYamlEndRe.MatchString(line) {
+			obs.inYaml = false
+		} else if m := tapDurationRe.FindStringSubmatch(line); m != nil {
+			// The regexp guarantees that this is a number.
+			ms, _ := strconv.ParseFloat(m[tapDurationRe.SubexpIndex("ms")], 64)
+			obs.records.amend(obs.landed.name, obs.landed.status,
+				time.Duration(ms*float64(time.Millisecond)))
+		}
+		return
+	}
+
+	if tapYamlStartRe.MatchString(line) && obs.landed.name != "" {
+		obs.inYaml = true
+		return
+	}
+	if tapSubtestRe.MatchString(line) {
+		// The next YAML blocks are about the subtests.
+		obs.landed = tapTest{}
+		return
+	}
+
+	if m := tapPlanRe.FindStringSubmatch(line); m != nil {
+		// The regexp guarantees that this is a number.
+		count, _ := strconv.Atoi(m[tapPlanRe.SubexpIndex("count")])
+		obs.total += count
+		obs.records.setProgress(obs.done, obs.total)
+		if obs.done == obs.total {
+			obs.records.abort(obs.placeholder())
+		}
+		return
+	}
+
+	if strings.HasPrefix(line, "Bail out!") {
+		obs.records.abort(obs.placeholder())
+		return
+	}
+
+	m := tapTestRe.FindStringSubmatch(line)
+	if m == nil {
+		return
+	}
+	obs.records.abort(obs.placeholder())
+	if num := m[tapTestRe.SubexpIndex("num")]; num != "" {
+		// The regexp guarantees that this is a number.
+		obs.next, _ = strconv.Atoi(num)
+	}
+	name := m[tapTestRe.SubexpIndex("desc")]
+	if name == "" {
+		name = obs.placeholder()
+	}
+	status := m[tapTestRe.SubexpIndex("ok")]
+	if directive := m[tapTestRe.SubexpIndex("directive")]; directive != "" {
+		status = strings.ToUpper(directive)
+	}
+
+	obs.records.land(name, status, now, now.Sub(obs.last))
+	obs.landed = tapTest{name: name, status: status, finished: now}
+	obs.last = now
+	obs.next++
+	obs.done++
+	obs.records.setProgress(obs.done, obs.total)
+	if obs.total == 0 || obs.done < obs.total {
+		obs.records.takeoff(obs.placeholder(), now)
+	}
+}
+
+// placeholder returns the name of the test in flight, which we do not know yet.
+func (obs *tapObserver) placeholder() string {
+	return fmt.Sprintf("#%d", obs.next)
+}
@@ -0,0 +1,83 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+func TestTapObserver(t *testing.T) {
+	t0 := time.Now()
+	records := feed(t0, newTapObserver, config{}, []line{
+		{0, "TAP version 14"},
+		{0, "1..5"},
+		{1 * time.Second, "ok 1 - parse empty input"},
+		{3 * time.Second, "not ok 2 - parse garbage"},
+		{3 * time.Second, "  ---"},
+		{3 * time.Second, "  message: unexpected token"},
+		{3 * time.Second, "  duration_ms: 1500"},
+		{3 * time.Second, "  ..."},
+		{4 * time.Second, "# Subtest: unicode"},
+		{4 * time.Second, "    ok 1 - ascii"},
+		{4 * time.Second, "    1..1"},
+		{6 * time.Second, "ok 3 - unicode"},
+		{7 * time.Second, "ok 4 - network # SKIP offline"},
+		{9 * time.Second, "not ok 5 - emoji # todo not implemented yet"},
+	})
+
+	assert.Check(t, cmp.DeepEqual(durations(records.landed), map[string]string{
+		"parse empty input": "1s ok",
+		"parse garbage":     "1.5s not ok",
+		"unicode":           "3s ok",
+		"network":           "1s SKIP",
+		"emoji":             "2s TODO",
+	}))
+	assert.Check(t, cmp.Len(flying(records), 0))
+	assert.Equal(t, records.done, 5)
+	assert.Equal(t, records.total, 5)
+}
+
+func TestTapObserverSubtestYaml(t *testing.T) {
+	t0 := time.Now()
+	records := feed(t0, newTapObserver, config{}, []line{
+		{0, "1..2"},
+		{1 * time.Second, "ok 1 - first"},
+		{2 * time.Second, "# Subtest: second"},
+		{2 * time.Second, "    ok 1 - inner"},
+		{2 * time.Second, "      ---"},
+		{2 * time.Second, "      duration_ms: 5000"},
+		{2 * time.Second, "      ..."},
+		{2 * time.Second, "    1..1"},
+		{3 * time.Second, "ok 2 - second"},
+		{3 * time.Second, "  ---"},
+		{3 * time.Second, "  extra:"},
+		{3 * time.Second, "    duration_ms: 7000"},
+		{3 * time.Second, "  duration_ms: 1500"},
+		{3 * time.Second, "  ..."},
+	})
+
+	assert.Check(t, cmp.DeepEqual(durations(records.landed), map[string]string{
+		"first":  "1s ok",
+		"second": "1.5s ok",
+	}))
+}
+
+func TestTapObserverInFlight(t *testing.T) {
+	t0 := time.Now()
+	records := feed(t0, newTapObserver, config{}, []line{
+		{0, "ok 1"},
+		{1 * time.Second, "ok - second"},
+	})
+
+	assert.Check(t, cmp.DeepEqual(durations(records.landed), map[string]string{
+		"#1":     "0s ok",
+		"second": "1s ok",
+	}))
+	assert.Check(t, cmp.Len(flying(records), 1))
+	assert.Equal(t, flying(records)["#3"].started, t0.Add(time.Second))
+}
@@ -0,0 +1,369 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+// line is a line of output of the child, seen at time t0 + at.
+type line struct {
+	at   time.Duration
+	text string
+}
+
+// feed passes lines to the observer created by newObserver with cfg and returns
+// the resulting records. As observeOutput, it skips the lines that the observer
+// does not want (see filterer).
+func feed(t0 time.Time, newObserver func(*records, config) observer, cfg config,
+	lines []line,
+) *records {
+	records := newRecords()
+	obs := newObserver(records, cfg)
+	if st, ok := obs.(starter); ok {
+		st.start(t0)
+	}
+	filter, _ := obs.(filterer)
+	for _, l := range lines {
+		if filter != nil && !filter.wants([]byte(l.text)) {
+			continue
+		}
+		obs.observe(l.text, t0.Add(l.at))
+	}
+	return records
+}
+
+// durations returns the duration and status of each flight in events, keyed by
+// display name.
+func durations(events []event) map[string]string {
+	durs := make(map[string]string, len(events))
+	for _, evt := range events {
+		durs[evt.displayName()] = evt.finished.Sub(evt.started).String() + " " + evt.status
+	}
+	return durs
+}
+
+// flying returns the flights in flight, keyed by display name.
+func flying(records *records) map[string]event {
+	flying := make(map[string]event, len(records.flying))
+	for _, evt := range records.flying {
+		flying[evt.displayName()] = evt
+	}
+	return flying
+}
+
+func TestPytestObserver(t *testing.T) {
+	t0 := time.Now()
+	records := feed(t0, newPytestObserver, config{}, []line{
+		{0, "test_fruits.py::test_apple"},
+		{1 * time.Second, "test_fruits.py::test_banana"},
+		{3 * time.Second, "[gw1] [ 50%] PASSED test_fruits.py::test_apple"},
+		{4 * time.Second, "some output"},
+	})
+
+	assert.Check(t, cmp.DeepEqual(durations(records.landed),
+		map[string]string{"test_fruits.py::test_apple": "3s PASSED"}))
+	assert.Check(t, cmp.Len(flying(records), 1))
+	assert.Check(t, cmp.Contains(flying(records), "test_fruits.py::test_banana"))
+}
+
+func TestPytestObserverRerun(t *testing.T) {
+	t0 := time.Now()
+	records := feed(t0, newPytestObserver, config{}, []line{
+		{0, "test_fruits.py::test_apple"},
+		{1 * time.Second, "[gw1] [ 50%] RERUN test_fruits.py::test_apple"},
+		{1 * time.Second, "test_fruits.py::test_apple"},
+		{3 * time.Second, "[gw1] [ 50%] RERUN test_fruits.py::test_apple"},
+		{3 * time.Second, "test_fruits.py::test_apple"},
+		{6 * time.Second, "[gw1] [100%] PASSED test_fruits.py::test_apple"},
+	})
+
+	assert.Check(t, cmp.DeepEqual(durations(records.landed), map[string]string{
+		"test_fruits.py::test_apple":             "1s RERUN",
+		"test_fruits.py::test_apple (attempt 2)": "2s RERUN",
+		"test_fruits.py::test_apple (attempt 3)": "3s PASSED",
+	}))
+	assert.Check(t, cmp.Len(records.flying, 0))
+	assert.DeepEqual(t, retriedFlights(records), []string{"test_fruits.py::test_apple"})
+}
+
+func TestPytestObserverWorkerCrash(t *testing.T) {
+	t0 := time.Now()
+	records := feed(t0, newPytestObserver, config{}, []line{
+		{0, "test_fruits.py::test_apple"},
+		{0, "test_fruits.py::test_banana"},
+		{1 * time.Second, "[gw1] [ 50%] PASSED test_fruits.py::test_banana"},
+		{4 * time.Second, "[gw2] node down: Not properly terminated"},
+		{4 * time.Second, "[gw2] [100%] FAILED test_fruits.py::test_apple"},
+		{4 * time.Second, "replacing crashed worker gw2"},
+		{5 * time.Second, "test_fruits.py::test_coconut"},
+		{6 * time.Second, "[gw2] [100%] FAILED test_fruits.py::test_coconut"},
+	})
+
+	assert.Check(t, cmp.DeepEqual(durations(records.landed), map[string]string{
+		"test_fruits.py::test_apple":   "4s CRASHED",
+		"test_fruits.py::test_banana":  "1s PASSED",
+		"test_fruits.py::test_coconut": "1s FAILED",
+	}))
+	assert.Check(t, cmp.Len(records.flying, 0))
+	assert.Assert(t, cmp.Len(records.crashes, 1))
+	assert.Equal(t, records.crashes[0].worker, "gw2")
+	assert.Equal(t, records.crashes[0].reason, "Not properly terminated")
+	assert.DeepEqual(t, records.crashes[0].flights, []string{"test_fruits.py::test_apple"})
+}
+
+func TestPytestObserverWorkerCrashWithoutFailed(t *testing.T) {
+	t0 := time.Now()
+	records := feed(t0, newPytestObserver, config{}, []line{
+		{0, "test_fruits.py::test_apple"},
+		{0, "test_fruits.py::test_banana"},
+		{1 * time.Second, "[gw1] [ 33%] PASSED test_fruits.py::test_banana"},
+		{1 * time.Second, "test_fruits.py::test_coconut"},
+		{3 * time.Second, "[gw2] node down: Not properly terminated"},
+		{3 * time.Second, "replacing crashed worker gw2"},
+		{5 * time.Second, "[gw1] node down: Not properly terminated"},
+		{5 * time.Second, "replacing crashed worker gw1"},
+	})
+
+	assert.Check(t, cmp.DeepEqual(durations(records.landed), map[string]string{
+		"test_fruits.py::test_apple":   "3s CRASHED",
+		"test_fruits.py::test_banana":  "1s PASSED",
+		"test_fruits.py::test_coconut": "4s CRASHED",
+	}))
+	assert.Check(t, cmp.Len(records.flying, 0))
+	assert.Assert(t, cmp.Len(records.crashes, 2))
+	assert.DeepEqual(t, records.crashes[0].flights, []string{"test_fruits.py::test_apple"})
+	assert.DeepEqual(t, records.crashes[1].flights, []string{"test_fruits.py::test_coconut"})
+}
+
+func TestPytestObserverWorkerCrashMidTest(t *testing.T) {
+	t0 := time.Now()
+	records := newRecords()
+	obs := newPytestObserver(records, config{}).(*pytestObserver)
+	for _, l := range []line{
+		{0, "test_fruits.py::test_apple"},
+		{0, "test_fruits.py::test_coconut"},
+		{1 * time.Second, "[gw1] [ 33%] PASSED test_fruits.py::test_apple"},
+		// Runs on gw1, which just landed test_apple.
+		{1 * time.Second, "test_fruits.py::test_banana"},
+		{2 * time.Second, "[gw2] [ 66%] PASSED test_fruits.py::test_coconut"},
+		{4 * time.Second, "[gw1] node down: Not properly terminated"},
+	} {
+		obs.observe(l.text, t0.Add(l.at))
+	}
+	// The run ends before gw1 is replaced.
+	assert.NilError(t, obs.finish(t0))
+
+	assert.Check(t, cmp.DeepEqual(durations(records.landed), map[string]string{
+		"test_fruits.py::test_apple":   "1s PASSED",
+		"test_fruits.py::test_coconut": "2s PASSED",
+		"test_fruits.py::test_banana":  "3s CRASHED",
+	}))
+	assert.Check(t, cmp.Len(records.flying, 0))
+	assert.Assert(t, cmp.Len(records.crashes, 1))
+	assert.Equal(t, records.crashes[0].worker, "gw1")
+	assert.DeepEqual(t, records.crashes[0].flights, []string{"test_fruits.py::test_banana"})
+}
+
+func TestPytestObserverIdleWorkersAreBounded(t *testing.T) {
+	t0 := time.Now()
+	obs := newPytestObserver(newRecords(), config{}).(*pytestObserver)
+	// Only landing lines, without the start lines of the tests.
+	for i := range 1000 {
+		obs.observe(fmt.Sprintf("[gw%d] [ 50%%] PASSED test_fruits.py::test_%d", i%2, i),
+			t0.Add(time.Duration(i)*time.Second))
+	}
+
+	assert.DeepEqual(t, obs.idle, []string{"gw0", "gw1"})
+}
+
+// chattyOutput returns lines of output of a chatty pytest run: for each test, a
+// start line, a landing line and noise lines of output.
+func chattyOutput(tests int, noise int) []byte {
+	var buf bytes.Buffer
+	for i := range tests {
+		fmt.Fprintf(&buf, "test_fruits.py::test_%d\n", i)
+		for j := range noise {
+			fmt.Fprintf(&buf, "DEBUG 2026-01-02 03:04:05 some log line %d of the code under test\n", j)
+		}
+		fmt.Fprintf(&buf, "[gw1] [ %d%%] PASSED test_fruits.py::test_%d\n", i*100/tests, i)
+	}
+	return buf.Bytes()
+}
+
+func TestObserveOutputCopiesAndObserves(t *testing.T) {
+	output := chattyOutput(100, 10)
+	records := newRecords()
+	obs := &lockedObserver{obs: newPytestObserver(records, config{})}
+	var dst bytes.Buffer
+
+	observeOutput(obs, "stdout", bytes.NewReader(output), &dst)
+
+	assert.Check(t, bytes.Equal(dst.Bytes(), output))
+	assert.Check(t, cmp.Len(records.landed, 100))
+	assert.Check(t, cmp.Len(records.flying, 0))
+}
+
+func TestObserveOutputIsByteExact(t *testing.T) {
+	long := strings.Repeat("x", 100*1024)
+	output := "progress 10%\rprogress 50%\rprogress 100%\n" +
+		"test_fruits.py::test_" + long + "\r\n" +
+		"\n" +
+		"test_fruits.py::test_apple"
+	records := newRecords()
+	obs := &lockedObserver{obs: newPytestObserver(records, config{})}
+	var dst bytes.Buffer
+
+	observeOutput(obs, "stdout", strings.NewReader(output), &dst)
+
+	assert.Equal(t, dst.String(), output)
+	assert.Check(t, cmp.Contains(flying(records), "test_fruits.py::test_"+long))
+	// The last line, even if not terminated.
+	assert.Check(t, cmp.Contains(flying(records), "test_fruits.py::test_apple"))
+}
+
+// errWriter is an io.Writer that always fails.
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestObserveOutputWriteError(t *testing.T) {
+	output := chattyOutput(3, 100)
+	records := newRecords()
+	obs := &lockedObserver{obs: newPytestObserver(records, config{})}
+
+	err := observeOutput(obs, "stdout", bytes.NewReader(output), errWriter{})
+
+	assert.Error(t, err, "writing stdout: broken pipe")
+	// The output is still drained and observed.
+	assert.Check(t, cmp.Len(records.landed, 3))
+}
+
+func TestLineWriter(t *testing.T) {
+	testCases := []struct {
+		name   string
+		writes []string
+		want   []string
+	}{
+		{
+			name:   "lines split across writes",
+			writes: []string{"ab", "c\nd", "e\nf"},
+			want:   []string{"abc", "de", "f"},
+		},
+		{
+			name:   "carriage returns",
+			writes: []string{"10%\r20%\r", "30%\r\ndone\r\n"},
+			want:   []string{"10%", "20%", "30%", "done"},
+		},
+		{
+			name:   "CR LF across writes",
+			writes: []string{"a\r", "\nb\r", "\n"},
+			want:   []string{"a", "b"},
+		},
+		{
+			name:   "empty lines",
+			writes: []string{"\n\r\n\r"},
+			want:   []string{"", "", ""},
+		},
+		{
+			name:   "too long",
+			writes: []string{strings.Repeat("a", maxLineLen-1), "bb", "b\n" + strings.Repeat("c", maxLineLen+1) + "\n"},
+			want:   []string{strings.Repeat("a", maxLineLen-1) + "b", strings.Repeat("c", maxLineLen)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			lw := &lineWriter{fn: func(line []byte) { got = append(got, string(line)) }}
+			for _, w := range tc.writes {
+				n, err := lw.Write([]byte(w))
+				assert.NilError(t, err)
+				assert.Equal(t, n, len(w))
+			}
+			lw.flush()
+
+			assert.DeepEqual(t, got, tc.want)
+		})
+	}
+}
+
+func TestObserveOutputDoesNotAllocatePerLine(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector allocates")
+	}
+	// Only noise lines: none of them passes the filter of the observer.
+	output := chattyOutput(0, 0)
+	for range 10_000 {
+		output = append(output, "some output of the code under test\n"...)
+	}
+	obs := &lockedObserver{obs: newPytestObserver(newRecords(), config{})}
+
+	allocs := testing.AllocsPerRun(10, func() {
+		observeOutput(obs, "stdout", bytes.NewReader(output), io.Discard)
+	})
+	// Only the buffers of observeOutput.
+	assert.Check(t, allocs <= 5, "allocs per run: %v", allocs)
+}
+
+func TestObserveOutputAllocatesOnlyToRecordMatchedLines(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector allocates")
+	}
+	const tests = 1000
+	output := chattyOutput(tests, 0)
+
+	allocs := testing.AllocsPerRun(10, func() {
+		obs := &lockedObserver{obs: newPytestObserver(newRecords(), config{})}
+		observeOutput(obs, "stdout", bytes.NewReader(output), io.Discard)
+	})
+	// Per matched line: the copy of the line, the indexes of its submatches and
+	// the path of the flight, plus the growth of the records.
+	perLine := allocs / (2 * tests)
+	assert.Check(t, perLine <= 4, "allocs per matched line: %v", perLine)
+}
+
+// Compare with BenchmarkCopy, which is the overhead of passing the output of the
+// command through timeit without observing it.
+func BenchmarkObserveOutput(b *testing.B) {
+	for _, bc := range []struct {
+		name  string
+		noise int
+	}{
+		{"tests only", 0},
+		{"10 lines per test", 10},
+		{"100 lines per test", 100},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			output := chattyOutput(1000, bc.noise)
+			b.SetBytes(int64(len(output)))
+			b.ReportAllocs()
+			for range b.N {
+				obs := &lockedObserver{obs: newPytestObserver(newRecords(), config{})}
+				observeOutput(obs, "stdout", bytes.NewReader(output), io.Discard)
+			}
+		})
+	}
+}
+
+func BenchmarkCopy(b *testing.B) {
+	output := chattyOutput(1000, 100)
+	b.SetBytes(int64(len(output)))
+	b.ReportAllocs()
+	for range b.N {
+		// Hide io.WriterTo and io.ReaderFrom, to copy in chunks as from a pipe.
+		io.Copy(struct{ io.Writer }{io.Discard}, struct{ io.Reader }{bytes.NewReader(output)})
+	}
+}
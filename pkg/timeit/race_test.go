@@ -0,0 +1,10 @@
+//go:build race
+
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+// The race detector allocates on its own: the tests that count the allocations
+// are skipped.
+const raceEnabled = true
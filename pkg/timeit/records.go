@@ -0,0 +1,356 @@
+// This code is released under the MIT License
+// Copyright (c) 2023 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type event struct {
+	name     string
+	status   string
+	started  time.Time
+	finished time.Time
+	// The groups containing the flight, followed by the label of the flight. For
+	// example: "test_fruits.py", "test_apple". Empty if the flight is not nested.
+	path []string
+	// The worker that ran the flight, if known (for example, pytest-xdist "gw3").
+	worker string
+	// Starting from 1, incremented each time a flight with the same name takes off
+	// (for example, a test rerun or the same test in different workers).
+	attempt int
+}
+
+// displayName returns the name of evt, followed by its attempt if not the first.
+func (evt event) displayName() string {
+	if evt.attempt > 1 {
+		return fmt.Sprintf("%s (attempt %d)", evt.name, evt.attempt)
+	}
+	return evt.name
+}
+
+func (evt event) duration() time.Duration {
+	return evt.finished.Sub(evt.started)
+}
+
+// flightKey identifies a flight among the ones with the same name.
+type flightKey struct {
+	worker  string
+	name    string
+	attempt int
+}
+
+type records struct {
+	mu     sync.Mutex
+	flying map[flightKey]event
+	// In landing order. Empty if stream is not nil.
+	landed []event
+	// If not nil, the landed flights are summarized in stream instead of being
+	// kept in landed, and the attempts of a landed flight are forgotten (see --top).
+	stream *flightStats
+	// Event name -> number of attempts so far.
+	attempts map[string]int
+	// Progress of the whole run, as reported by the observed command.
+	// Zero total means unknown.
+	done  int
+	total int
+	// Points in time reported by the command, in order.
+	marks []mark
+	// Workers that crashed, in order.
+	crashes []crash
+	// The last landed flights, at most maxRecent, oldest first (see --tui).
+	recent []event
+	// The last failed flights, at most maxRecent, oldest first, and the number of
+	// all the failed flights.
+	failures []event
+	failed   int
+}
+
+// maxRecent is the number of recent landings and failures kept by records.
+const maxRecent = 5
+
+// A mark is a point in time, relative to the start of the command.
+type mark struct {
+	name string
+	at   time.Duration
+}
+
+// A crash is a worker that died while running the flights in flights.
+type crash struct {
+	worker  string
+	reason  string
+	flights []string
+}
+
+// statusCrashed is the status of a flight whose worker crashed.
+const statusCrashed = "CRASHED"
+
+func newRecords() *records {
+	return &records{
+		flying:   make(map[flightKey]event, 100),
+		landed:   make([]event, 0, 100),
+		attempts: make(map[string]int, 100),
+	}
+}
+
+// takeoff records the start of flight name, nested as path (see event.path).
+func (r *records) takeoff(name string, now time.Time, path ...string) {
+	r.takeoffOn("", name, now, path...)
+}
+
+// takeoffOn is like takeoff, for a flight run by worker. It returns the key of
+// the flight, for landKey.
+func (r *records) takeoffOn(worker string, name string, now time.Time,
+	path ...string,
+) flightKey {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts[name]++
+	key := flightKey{worker: worker, name: name, attempt: r.attempts[name]}
+	r.flying[key] = event{
+		name: name, started: now, path: path, worker: worker, attempt: key.attempt,
+	}
+	return key
+}
+
+// land records the end of flight name with status. If we never saw the flight
+// taking off, it is assumed to have started at now - dur, nested as path.
+// If more than one flight with this name is in flight, the oldest attempt lands.
+func (r *records) land(name string, status string, now time.Time, dur time.Duration,
+	path ...string,
+) {
+	r.landOn("", name, status, now, dur, path...)
+}
+
+// landOn is like land, for a flight run by worker. A flight that took off on an
+// unknown worker can land on a known one.
+func (r *records) landOn(worker string, name string, status string, now time.Time,
+	dur time.Duration, path ...string,
+) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, ok := r.findFlying(worker, name)
+	evt := r.flying[key]
+	if ok {
+		delete(r.flying, key)
+	} else {
+		r.attempts[name]++
+		evt = event{name: name, started: now.Add(-dur), path: path,
+			attempt: r.attempts[name]}
+	}
+	evt.worker = worker
+	evt.status = status
+	evt.finished = now
+	r.appendLanded(evt)
+}
+
+// landKey records the end of the flight with key, with status. It returns false if
+// the flight is not in flight (for example, it already landed).
+func (r *records) landKey(key flightKey, status string, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	evt, ok := r.flying[key]
+	if !ok {
+		return false
+	}
+	delete(r.flying, key)
+	evt.status = status
+	evt.finished = now
+	r.appendLanded(evt)
+	return true
+}
+
+// appendLanded adds evt to the landed flights. Must be called with r.mu held.
+func (r *records) appendLanded(evt event) {
+	r.recent = appendRecent(r.recent, evt)
+	if isFailure(evt.status) {
+		r.failures = appendRecent(r.failures, evt)
+		r.failed++
+	}
+	if r.stream == nil {
+		r.landed = append(r.landed, evt)
+		return
+	}
+	r.stream.add(evt)
+	// Forget the name, unless it has more than one attempt, so that memory does
+	// not grow with the number of flights. A flight that runs again after landing
+	// is then not numbered as a new attempt.
+	if evt.attempt == 1 && r.attempts[evt.name] == 1 {
+		delete(r.attempts, evt.name)
+	}
+}
+
+// appendRecent appends evt to recent, dropping the oldest events beyond maxRecent.
+func appendRecent(recent []event, evt event) []event {
+	if len(recent) == maxRecent {
+		recent = slices.Delete(recent, 0, 1)
+	}
+	return append(recent, evt)
+}
+
+// isFailure returns true if status is a failure, in the vocabulary of the
+// observed tools (FAILED, FAIL, ERROR, not ok, Error 2, ...).
+func isFailure(status string) bool {
+	status = strings.ToUpper(status)
+	return strings.Contains(status, "FAIL") || strings.HasPrefix(status, "ERROR") ||
+		status == "NOT OK" || status == statusCrashed
+}
+
+// findFlying returns the key of the oldest attempt of flight name in flight on
+// worker or, if none, on an unknown worker. Must be called with r.mu held.
+func (r *records) findFlying(worker string, name string) (flightKey, bool) {
+	for _, w := range []string{worker, ""} {
+		for attempt := 1; attempt <= r.attempts[name]; attempt++ {
+			key := flightKey{worker: w, name: name, attempt: attempt}
+			if _, ok := r.flying[key]; ok {
+				return key, true
+			}
+		}
+	}
+	return flightKey{}, false
+}
+
+// amend changes the status of the last landed flight name and, if dur is not
+// zero, its duration. It is for observers that learn more about a flight only
+// after its landing.
+func (r *records) amend(name string, status string, dur time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.amendRecent(name, status, dur)
+	if r.stream != nil {
+		// Only the last landed flight can be amended.
+		if evt := r.stream.pending; evt != nil && evt.name == name {
+			evt.status = status
+			if dur != 0 {
+				evt.started = evt.finished.Add(-dur)
+			}
+		}
+		return
+	}
+	for i := len(r.landed) - 1; i >= 0; i-- {
+		if r.landed[i].name == name {
+			r.landed[i].status = status
+			if dur != 0 {
+				r.landed[i].started = r.landed[i].finished.Add(-dur)
+			}
+			return
+		}
+	}
+}
+
+// amendRecent is amend for the recent landings and failures. Must be called with
+// r.mu held.
+func (r *records) amendRecent(name string, status string, dur time.Duration) {
+	i := len(r.recent) - 1
+	for i >= 0 && r.recent[i].name != name {
+		i--
+	}
+	if i < 0 {
+		return
+	}
+	evt := &r.recent[i]
+	wasFailure := isFailure(evt.status)
+	evt.status = status
+	if dur != 0 {
+		evt.started = evt.finished.Add(-dur)
+	}
+	switch {
+	case !wasFailure && isFailure(status):
+		r.failures = appendRecent(r.failures, *evt)
+		r.failed++
+	case wasFailure && !isFailure(status):
+		r.failed--
+		if k := len(r.failures) - 1; k >= 0 && r.failures[k].name == name {
+			r.failures = r.failures[:k]
+		}
+	case isFailure(status):
+		if k := len(r.failures) - 1; k >= 0 && r.failures[k].name == name {
+			r.failures[k] = *evt
+		}
+	}
+}
+
+// forgetLanded forgets the landed flights, for observers that learn all of them
+// again only at the end (see ninjaObserver.finish).
+func (r *records) forgetLanded() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.landed = r.landed[:0]
+	r.recent = r.recent[:0]
+	r.failures = r.failures[:0]
+	r.failed = 0
+	if r.stream != nil {
+		r.stream = newFlightStats(r.stream.slowest.k)
+	}
+	clear(r.attempts)
+}
+
+// abort forgets flight name, without landing it.
+func (r *records) abort(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if key, ok := r.findFlying("", name); ok {
+		delete(r.flying, key)
+	}
+}
+
+// crash records that worker crashed for reason. The flights that were running on
+// it land with landOnCrashed, since only the observer can tell which they are.
+func (r *records) crash(worker string, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.crashes = append(r.crashes, crash{worker: worker, reason: reason})
+}
+
+// landOnCrashed is like landOn with status CRASHED, for a flight that was running
+// on worker when it crashed (see crash). The flight is added to the last crash of
+// worker.
+func (r *records) landOnCrashed(worker string, name string, now time.Time,
+	path ...string,
+) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, ok := r.findFlying(worker, name)
+	evt := r.flying[key]
+	if ok {
+		delete(r.flying, key)
+	} else {
+		r.attempts[name]++
+		evt = event{name: name, started: now, path: path, attempt: r.attempts[name]}
+	}
+	evt.worker = worker
+	evt.status = statusCrashed
+	evt.finished = now
+	r.appendLanded(evt)
+
+	// The last crash of worker, if any.
+	i := len(r.crashes) - 1
+	for i >= 0 && r.crashes[i].worker != worker {
+		i--
+	}
+	if i < 0 {
+		r.crashes = append(r.crashes, crash{worker: worker})
+		i = len(r.crashes) - 1
+	}
+	r.crashes[i].flights = append(r.crashes[i].flights, evt.displayName())
+}
+
+// addMark records mark name at time at since the start of the command.
+func (r *records) addMark(name string, at time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.marks = append(r.marks, mark{name: name, at: at})
+}
+
+// setProgress records that done out of total flights have landed.
+func (r *records) setProgress(done int, total int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.done = done
+	r.total = total
+}
@@ -0,0 +1,97 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+func TestRecordsSameNameConcurrentFlights(t *testing.T) {
+	t0 := time.Now()
+	records := newRecords()
+	records.takeoffOn("gw1", "test_a", t0)
+	records.takeoffOn("gw2", "test_a", t0.Add(1*time.Second))
+	records.takeoff("test_a", t0.Add(2*time.Second))
+	assert.Check(t, cmp.Len(records.flying, 3))
+
+	// Lands the flight on its worker, even if not the oldest.
+	records.landOn("gw2", "test_a", "PASSED", t0.Add(3*time.Second), 0)
+	// No flight on gw3: lands the oldest flight on an unknown worker.
+	records.landOn("gw3", "test_a", "FAILED", t0.Add(5*time.Second), 0)
+	records.landOn("gw1", "test_a", "PASSED", t0.Add(6*time.Second), 0)
+
+	assert.Check(t, cmp.DeepEqual(durations(records.landed), map[string]string{
+		"test_a":             "6s PASSED",
+		"test_a (attempt 2)": "2s PASSED",
+		"test_a (attempt 3)": "3s FAILED",
+	}))
+	assert.Check(t, cmp.Len(records.flying, 0))
+	assert.Equal(t, records.landed[1].worker, "gw3")
+	assert.Equal(t, records.attempts["test_a"], 3)
+}
+
+func TestRecordsAmend(t *testing.T) {
+	t0 := time.Now()
+	records := newRecords()
+	records.land("test_a", "ok", t0.Add(1*time.Second), time.Second)
+	records.land("test_a", "ok", t0.Add(5*time.Second), time.Second)
+	records.amend("test_a", "not ok", 3*time.Second)
+
+	assert.Check(t, cmp.DeepEqual(durations(records.landed), map[string]string{
+		"test_a":             "1s ok",
+		"test_a (attempt 2)": "3s not ok",
+	}))
+}
+
+func TestRecordsCrash(t *testing.T) {
+	t0 := time.Now()
+	records := newRecords()
+	records.takeoff("test_a", t0)
+	records.takeoff("test_b", t0)
+	records.crash("gw1", "killed")
+	records.landOnCrashed("gw1", "test_a", t0.Add(3*time.Second))
+	// Never seen taking off.
+	records.landOnCrashed("gw1", "test_c", t0.Add(3*time.Second))
+
+	assert.Check(t, cmp.DeepEqual(durations(records.landed), map[string]string{
+		"test_a": "3s CRASHED",
+		"test_c": "0s CRASHED",
+	}))
+	assert.Check(t, cmp.Len(records.flying, 1))
+	assert.Assert(t, cmp.Len(records.crashes, 1))
+	assert.Equal(t, records.crashes[0].worker, "gw1")
+	assert.Equal(t, records.crashes[0].reason, "killed")
+	assert.DeepEqual(t, records.crashes[0].flights, []string{"test_a", "test_c"})
+	assert.DeepEqual(t, crashedWorkers(records.crashes), []string{"gw1"})
+}
+
+func TestRecordsRecentAndFailures(t *testing.T) {
+	t0 := time.Now()
+	records := newRecords()
+	for i, status := range []string{"ok", "FAILED", "ok", "ok", "ok", "ok", "ok"} {
+		records.land(fmt.Sprintf("test_%d", i), status, t0.Add(time.Second), 0)
+	}
+	// Turns the last landing into a failure.
+	records.amend("test_6", "not ok", 0)
+
+	var recent []string
+	for _, evt := range records.recent {
+		recent = append(recent, evt.name)
+	}
+	assert.Check(t, cmp.DeepEqual(recent,
+		[]string{"test_2", "test_3", "test_4", "test_5", "test_6"}))
+	assert.Check(t, cmp.Len(records.failures, 2))
+	assert.Equal(t, records.failures[1].status, "not ok")
+	assert.Equal(t, records.failed, 2)
+
+	// And back.
+	records.amend("test_6", "ok", 0)
+	assert.Check(t, cmp.Len(records.failures, 1))
+	assert.Equal(t, records.failed, 1)
+}
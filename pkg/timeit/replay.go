@@ -0,0 +1,325 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+)
+
+type replayConfig struct {
+	NoColor        bool          `help:"Disable color output."`
+	TickerDuration time.Duration `name:"ticker" placeholder:"DURATION" help:"Print a status line each DURATION of the recorded time."`
+	Observe        string        `required:"" placeholder:"FORMAT" help:"Observe the recorded output according to FORMAT. Supported formats: ansible, buildkit, ctest, json, libtest, make, ninja, pytest, sections, tap."`
+	JSON           JSONOptions   `embed:"" prefix:"json-" group:"--observe=json"`
+	Top            int           `placeholder:"N" help:"Keep in memory only the N slowest flights (see timeit --help)."`
+	NinjaLog       string        `placeholder:"FILE" group:"--observe=ninja" help:"Read the durations of the build edges from FILE, the .ninja_log of the recorded build. By default, the durations are the recorded ones."`
+	Timing         string        `placeholder:"FILE" help:"FILE is the timing file of a log recorded by script -t (classic or advanced format), instead of a transcript."`
+	Quiet          bool          `short:"q" help:"Do not print the recorded output of the command."`
+
+	File string `arg:"" help:"Transcript written by timeit --log or, with --timing, log written by script."`
+}
+
+// replayMain is the main of "timeit replay", with the arguments after "replay".
+func replayMain(args []string) int {
+	var cfg replayConfig
+	parser := kong.Must(&cfg,
+		kong.Name("timeit replay"),
+		kong.Description("Replay a recorded run through an observer, with the recorded times."),
+		kong.UsageOnError(),
+		kong.ConfigureHelp(kong.HelpOptions{
+			Compact: false,
+			Summary: true,
+		}))
+	_, err := parser.Parse(args)
+	parser.FatalIfErrorf(err)
+
+	if _, ok := observers[cfg.Observe]; !ok {
+		fmt.Fprintf(os.Stderr,
+			"timeit: unknown --observe=%s; must be one of: %s\n",
+			cfg.Observe, strings.Join(observerNames(), ", "))
+		return 1
+	}
+	if !isatty.IsTerminal(os.Stderr.Fd()) || cfg.NoColor {
+		color.NoColor = true
+	}
+
+	rec, err := readRecording(cfg.File, cfg.Timing)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "timeit: %s\n", err)
+		return 1
+	}
+	stdout, stderr := io.Writer(os.Stdout), io.Writer(os.Stderr)
+	if cfg.Quiet {
+		stdout, stderr = io.Discard, io.Discard
+	}
+	res := replay(rec, config{
+		TickerDuration: cfg.TickerDuration,
+		Observe:        cfg.Observe,
+		JSON:           cfg.JSON.withDefaults(),
+		Top:            cfg.Top,
+		NinjaLog:       cfg.NinjaLog,
+	}, stdout, stderr, os.Stderr)
+	color.New(color.FgMagenta, color.Bold).Fprint(os.Stderr, res.Report())
+	return 0
+}
+
+// A recording is a run read from a transcript (see --log) or from a log with its
+// timing file.
+type recording struct {
+	// The wall clock at the start of the command. Zero if unknown.
+	start time.Time
+	// The status of the command, from the results. Empty if unknown.
+	status string
+	// The time of the last record.
+	end    time.Duration
+	chunks []chunk
+}
+
+// A chunk is some output of the command, at time at since its start.
+type chunk struct {
+	at     time.Duration
+	stream int // muxStdout or muxStderr
+	data   []byte
+}
+
+// readRecording reads a transcript from file or, if timing is not empty, a log
+// from file with its timing file.
+func readRecording(file string, timing string) (recording, error) {
+	fi, err := os.Open(file)
+	if err != nil {
+		return recording{}, err
+	}
+	defer fi.Close()
+	if timing == "" {
+		rec, err := readTranscript(fi)
+		if err != nil {
+			return recording{}, fmt.Errorf("reading transcript %s: %s", file, err)
+		}
+		return rec, nil
+	}
+	ti, err := os.Open(timing)
+	if err != nil {
+		return recording{}, err
+	}
+	defer ti.Close()
+	rec, err := readScriptLog(fi, ti)
+	if err != nil {
+		return recording{}, fmt.Errorf("reading log %s with timing %s: %s", file, timing, err)
+	}
+	return rec, nil
+}
+
+// readTranscript reads a transcript written by --log (see transcript).
+func readTranscript(r io.Reader) (recording, error) {
+	var rec recording
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLen+64)
+	if !scanner.Scan() || scanner.Text() != transcriptHeader {
+		if err := scanner.Err(); err != nil {
+			return rec, err
+		}
+		return rec, errors.New("not a timeit transcript (see --log); for a log " +
+			"recorded by script, pass its timing file with --timing")
+	}
+	var inResults bool
+	for lineNum := 2; scanner.Scan(); lineNum++ {
+		secsStr, rest, _ := strings.Cut(scanner.Text(), " ")
+		tag, text, _ := strings.Cut(rest, " ")
+		secs, err := strconv.ParseFloat(secsStr, 64)
+		if err != nil || secs < 0 {
+			return rec, fmt.Errorf("line %d: malformed record: %q", lineNum, scanner.Text())
+		}
+		at := time.Duration(secs * float64(time.Second))
+		rec.end = max(rec.end, at)
+		switch tag {
+		case tagStart:
+			if rec.start, err = time.Parse(time.RFC3339Nano, text); err != nil {
+				return rec, fmt.Errorf("line %d: %s", lineNum, err)
+			}
+		case tagStdout:
+			rec.chunks = append(rec.chunks, chunk{at: at, stream: muxStdout,
+				data: []byte(text + "\n")})
+		case tagStderr:
+			rec.chunks = append(rec.chunks, chunk{at: at, stream: muxStderr,
+				data: []byte(text + "\n")})
+		case tagResult:
+			// The status is the line after the title of the results.
+			if inResults && rec.status == "" {
+				rec.status = strings.TrimSpace(text)
+			}
+			inResults = strings.TrimSpace(text) == "timeit results:"
+		}
+		// Other tags (cmd, timeit, and the ones of future versions) are ignored.
+	}
+	return rec, scanner.Err()
+}
+
+// readScriptLog reads log, the typescript written by script, with its timing
+// file, as written by script -t (classic format, "DELAY BYTES") or by script
+// --log-timing (advanced format, "TYPE DELAY BYTES"). Only the output is read.
+// The delays are relative to the previous entry.
+func readScriptLog(log io.Reader, timing io.Reader) (recording, error) {
+	var rec recording
+	logR := bufio.NewReader(log)
+	// The header of the typescript is not in the timing.
+	header, err := logR.Peek(len("Script started on "))
+	if err == nil && string(header) == "Script started on " {
+		if _, err := logR.ReadString('\n'); err != nil {
+			return rec, fmt.Errorf("reading header: %s", err)
+		}
+	}
+
+	var at time.Duration
+	scanner := bufio.NewScanner(timing)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		kind := "O"
+		if _, err := strconv.ParseFloat(fields[0], 64); err != nil {
+			// Advanced format.
+			kind, fields = fields[0], fields[1:]
+		}
+		if len(fields) < 2 {
+			return rec, fmt.Errorf("timing line %d: malformed entry: %q", lineNum,
+				scanner.Text())
+		}
+		delay, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil || delay < 0 {
+			return rec, fmt.Errorf("timing line %d: malformed delay: %q", lineNum,
+				scanner.Text())
+		}
+		at += time.Duration(delay * float64(time.Second))
+		rec.end = at
+		if kind != "O" {
+			// Input (I), header (H) and signal (S) entries.
+			continue
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil || n < 0 {
+			return rec, fmt.Errorf("timing line %d: malformed size: %q", lineNum,
+				scanner.Text())
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(logR, data); err != nil {
+			return rec, fmt.Errorf("timing line %d: reading %d bytes: %s", lineNum, n, err)
+		}
+		rec.chunks = append(rec.chunks, chunk{at: at, stream: muxStdout, data: data})
+	}
+	if err := scanner.Err(); err != nil {
+		return rec, err
+	}
+	// The trailer of the typescript, if any, has the exit code of the command.
+	trailer, _ := io.ReadAll(logR)
+	if m := scriptExitRe.FindSubmatch(trailer); m != nil {
+		rec.status = "command succeeded"
+		if string(m[1]) != "0" {
+			rec.status = "command failed: exit status " + string(m[1])
+		}
+	}
+	return rec, nil
+}
+
+// scriptExitRe matches the exit code in the trailer of a typescript:
+//
+//	Script done on 2026-10-18 22:38:26+00:00 [COMMAND_EXIT_CODE="1"]
+var scriptExitRe = regexp.MustCompile(`(?m)^Script done on .*\[COMMAND_EXIT_CODE="(\d+)"\]`)
+
+// replay feeds the output of rec to the observer of cfg, with the recorded times,
+// writing the output to stdout and stderr and the ticker to out. It returns the
+// results.
+func replay(rec recording, cfg config, stdout io.Writer, stderr io.Writer,
+	out io.Writer,
+) Result {
+	t0 := rec.start
+	if t0.IsZero() {
+		t0 = time.Unix(0, 0)
+	}
+	records := newRecords()
+	if cfg.Top > 0 {
+		records.stream = newFlightStats(cfg.Top)
+	}
+	obs := observers[cfg.Observe](records, cfg)
+	if st, ok := obs.(starter); ok {
+		st.start(t0)
+	}
+	filter, _ := obs.(filterer)
+
+	// The recorded time of the line being observed.
+	var now time.Time
+	var lines [2]*lineWriter
+	for i := range lines {
+		lines[i] = &lineWriter{fn: func(line []byte) {
+			// As when running, only some observers read also stderr.
+			if i == muxStderr && !stderrObservers[cfg.Observe] {
+				return
+			}
+			if filter == nil || filter.wants(line) {
+				obs.observe(string(line), now)
+			}
+		}}
+	}
+	dsts := [2]io.Writer{stdout, stderr}
+
+	chroma := color.New(color.FgMagenta, color.Bold)
+	dur := cfg.TickerDuration
+	tw := newTickerWriter()
+	nextTick := dur
+	// tickUntil prints the tickers up to at.
+	tickUntil := func(at time.Duration) {
+		for ; dur > 0 && nextTick <= at; nextTick += dur {
+			io.WriteString(out,
+				chroma.Sprintf("%s\n", tw.ticker(t0.Add(nextTick), t0, dur, true, records)))
+		}
+	}
+
+	for _, c := range rec.chunks {
+		tickUntil(c.at)
+		now = t0.Add(c.at)
+		dsts[c.stream].Write(c.data)
+		lines[c.stream].Write(c.data)
+	}
+	now = t0.Add(rec.end)
+	for _, lw := range lines {
+		lw.flush()
+	}
+	tickUntil(rec.end)
+
+	var errs []error
+	if fin, ok := obs.(finisher); ok {
+		if err := fin.finish(t0); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	status := rec.status
+	if status == "" {
+		status = "command status unknown"
+	}
+	precision := dur / 100
+	if precision == 0 {
+		precision = time.Millisecond
+	}
+	res := Result{
+		Status:    status,
+		Real:      rec.end,
+		Errors:    errs,
+		records:   records,
+		precision: precision,
+	}
+	res.Flights, res.Marks = flightsAndMarks(records)
+	return res
+}
@@ -0,0 +1,142 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestReadTranscriptRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	t0 := time.Date(2026, 10, 18, 22, 0, 0, 0, time.UTC)
+	tr := newTranscript(&buf, t0, []string{"pytest"})
+	stdout := tr.stream(tagStdout)
+	stderr := tr.stream(tagStderr)
+	fmt.Fprint(stdout, "one\ntwo")
+	fmt.Fprint(stderr, "error\n")
+	stdout.flush()
+	tr.text(tagResult, "\ntimeit results:\n    command failed: exit status 2\n")
+	assert.NilError(t, tr.flush())
+
+	rec, err := readTranscript(&buf)
+	assert.NilError(t, err)
+	assert.Assert(t, rec.start.Equal(t0))
+	assert.Equal(t, rec.status, "command failed: exit status 2")
+	var got []string
+	for _, c := range rec.chunks {
+		got = append(got, fmt.Sprintf("%d %q", c.stream, c.data))
+	}
+	assert.DeepEqual(t, got, []string{
+		fmt.Sprintf("%d %q", muxStdout, "one\n"),
+		fmt.Sprintf("%d %q", muxStderr, "error\n"),
+		fmt.Sprintf("%d %q", muxStdout, "two\n"),
+	})
+}
+
+func TestReadTranscriptErrors(t *testing.T) {
+	type testCase struct {
+		name    string
+		input   string
+		wantErr string
+	}
+
+	test := func(t *testing.T, tc testCase) {
+		_, err := readTranscript(strings.NewReader(tc.input))
+		assert.Error(t, err, tc.wantErr)
+	}
+
+	testCases := []testCase{
+		{
+			name:  "empty",
+			input: "",
+			wantErr: "not a timeit transcript (see --log); for a log recorded by " +
+				"script, pass its timing file with --timing",
+		},
+		{
+			name:    "malformed seconds",
+			input:   transcriptHeader + "\n0.1 out ok\nbanana out ko\n",
+			wantErr: `line 3: malformed record: "banana out ko"`,
+		},
+		{
+			name:  "malformed start",
+			input: transcriptHeader + "\n0.0 start yesterday\n",
+			wantErr: `line 2: parsing time "yesterday" as "2006-01-02T15:04:05.999999999Z07:00": ` +
+				`cannot parse "yesterday" as "2006"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) { test(t, tc) })
+	}
+}
+
+func TestReadScriptLog(t *testing.T) {
+	type testCase struct {
+		name       string
+		log        string
+		timing     string
+		wantChunks []string
+		wantEnd    time.Duration
+		wantStatus string
+		wantErr    string
+	}
+
+	test := func(t *testing.T, tc testCase) {
+		rec, err := readScriptLog(strings.NewReader(tc.log), strings.NewReader(tc.timing))
+		if tc.wantErr != "" {
+			assert.Error(t, err, tc.wantErr)
+			return
+		}
+		assert.NilError(t, err)
+		var got []string
+		for _, c := range rec.chunks {
+			got = append(got, fmt.Sprintf("%v %q", c.at, c.data))
+		}
+		assert.DeepEqual(t, got, tc.wantChunks)
+		assert.Equal(t, rec.end, tc.wantEnd)
+		assert.Equal(t, rec.status, tc.wantStatus)
+	}
+
+	testCases := []testCase{
+		{
+			name:       "classic, without header",
+			log:        "one\ntwo\n",
+			timing:     "0.5 4\n1.25 4\n",
+			wantChunks: []string{`500ms "one\n"`, `1.75s "two\n"`},
+			wantEnd:    1750 * time.Millisecond,
+		},
+		{
+			name: "advanced, with header and trailer",
+			log: "Script started on 2026-10-18 22:00:00+00:00 [COMMAND=\"false\"]\n" +
+				"one\ntwo\n" +
+				"\nScript done on 2026-10-18 22:00:01+00:00 [COMMAND_EXIT_CODE=\"1\"]\n",
+			timing:     "H 0.000000 TERM xterm\nO 0.5 4\nI 0.5 1\nO 0.25 4\nS 0.5 SIGWINCH ROWS=24\n",
+			wantChunks: []string{`500ms "one\n"`, `1.25s "two\n"`},
+			wantEnd:    1750 * time.Millisecond,
+			wantStatus: "command failed: exit status 1",
+		},
+		{
+			name:    "timing longer than log",
+			log:     "one\n",
+			timing:  "0.5 4\n0.5 4\n",
+			wantErr: "timing line 2: reading 4 bytes: EOF",
+		},
+		{
+			name:    "malformed delay",
+			log:     "one\n",
+			timing:  "0.5 4\nO -1 4\n",
+			wantErr: `timing line 2: malformed delay: "O -1 4"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) { test(t, tc) })
+	}
+}
@@ -0,0 +1,476 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+)
+
+// Options configures Run.
+type Options struct {
+	// The command to run: the executable followed by its arguments.
+	Command []string
+	// The stdin of the command. If nil, the command reads from the null device.
+	Stdin io.Reader
+	// Where to copy the stdout and stderr of the command. Stderr receives also the
+	// ticker. If nil, os.Stdout and os.Stderr. The output of timeit is colored only
+	// on a terminal.
+	Stdout io.Writer
+	Stderr io.Writer
+	// If not zero, write the ticker to Stderr each Ticker.
+	Ticker time.Duration
+	// If not empty, observe the output of the command according to this format, one
+	// of ObserverFormats.
+	Observer string
+	// For Observer "json". An empty field takes its default.
+	JSON JSONOptions
+	// For Observer "ninja": the ninja log. If empty, ".ninja_log".
+	NinjaLog string
+	// Pass to the command a file descriptor where it can write timing records (see
+	// README). Not available on Windows.
+	TimingFD bool
+	// If not zero, keep only the Top slowest flights (see Result.Flights).
+	Top int
+	// If true, with Ticker and Stderr a terminal, redraw the ticker in place as a
+	// dashboard at the bottom of the terminal, with the output of the command
+	// scrolling above it.
+	TUI bool
+	// If true and Stderr is a terminal, show the progress in the window title and,
+	// if the terminal supports it, as OSC 9;4 progress (see README).
+	TermProgress bool
+	// If not empty, prefix each line of the command output with its time: "elapsed"
+	// since the start or "wall" clock. The lines of stdout and of stderr have a
+	// different tag (see README).
+	Timestamps string
+	// If not nil, write there a transcript of the run: the output of the command,
+	// the ticker and the results, each line with its time (see README).
+	Log io.Writer
+
+	// Set by Main: report and ignore SIGINT, leaving it to the command.
+	handleSignals bool
+}
+
+// JSONOptions maps the fields of each line of a JSON-lines log to flights.
+// A path is a sequence of field names separated by dots, for example "task.name".
+type JSONOptions struct {
+	Event  string `default:"event" placeholder:"PATH" help:"Path of the field with the event type (default: ${default})."`
+	Begin  string `default:"start" placeholder:"VALUE" help:"Event type of the start of a flight (default: ${default})."`
+	End    string `default:"end" placeholder:"VALUE" help:"Event type of the end of a flight (default: ${default})."`
+	Name   string `default:"name" placeholder:"PATH" help:"Path of the field with the flight name (default: ${default})."`
+	Status string `default:"status" placeholder:"PATH" help:"Path of the field with the flight status, optional in the log (default: ${default})."`
+}
+
+// withDefaults returns opts, with the defaults for the empty fields.
+func (opts JSONOptions) withDefaults() JSONOptions {
+	if opts.Event == "" {
+		opts.Event = "event"
+	}
+	if opts.Begin == "" {
+		opts.Begin = "start"
+	}
+	if opts.End == "" {
+		opts.End = "end"
+	}
+	if opts.Name == "" {
+		opts.Name = "name"
+	}
+	if opts.Status == "" {
+		opts.Status = "status"
+	}
+	return opts
+}
+
+// ObserverFormats returns the sorted list of formats supported by
+// Options.Observer.
+func ObserverFormats() []string {
+	return observerNames()
+}
+
+// Result is the outcome of Run.
+type Result struct {
+	// Human-readable status of the command, for example "command succeeded".
+	Status string
+	// The exit code of the command. If the command was terminated by a signal,
+	// 128 + the signal number, as the shell does.
+	ExitCode int
+	// Wall-clock duration of the command.
+	Real time.Duration
+	// CPU time of the command, in user mode and in system mode.
+	User   time.Duration
+	System time.Duration
+	// System-dependent resource usage of the command (*syscall.Rusage on Unix), or
+	// nil. See os.ProcessState.SysUsage.
+	Rusage any
+	// The landed flights, in landing order. With Options.Top, only the slowest.
+	Flights []Flight
+	// The marks written to the timing file descriptor, in order.
+	Marks []Mark
+	// The errors of timeit itself while running the command, if any.
+	Errors []error
+
+	// For Report. Nil if not summarizing flights.
+	records   *records
+	precision time.Duration
+}
+
+// A Flight is an operation of the command, as observed by timeit, for example a
+// test.
+type Flight struct {
+	Name   string
+	Status string
+	// The groups containing the flight, followed by the label of the flight, if
+	// nested. For example: "test_fruits.py", "test_apple".
+	Path []string
+	// The worker that ran the flight, if known (for example, pytest-xdist "gw3").
+	Worker string
+	// Starting from 1, incremented each time a flight with the same name takes off.
+	Attempt  int
+	Started  time.Time
+	Finished time.Time
+}
+
+// Duration returns how long the flight took.
+func (f Flight) Duration() time.Duration {
+	return f.Finished.Sub(f.Started)
+}
+
+// A Mark is a point in time, relative to the start of the command.
+type Mark struct {
+	Name string
+	At   time.Duration
+}
+
+// Report returns the results, as printed by the timeit command.
+func (res Result) Report() string {
+	return results(res.Status, res.Real, res.precision, res.records, res.Errors)
+}
+
+// Run runs opts.Command and waits for it to terminate, copying its output to
+// opts.Stdout and opts.Stderr and writing there the ticker, if requested.
+//
+// If the command cannot be started, Run returns an error, with Result.Status
+// describing it. If the command runs, Run returns a nil error, also when the
+// command fails: see Result.ExitCode. If ctx is done before the command
+// terminates, the command is killed and Run returns ctx.Err().
+func Run(ctx context.Context, opts Options) (Result, error) {
+	if len(opts.Command) == 0 {
+		return Result{Status: "no command", ExitCode: 1}, errors.New("no command")
+	}
+	if opts.Observer != "" {
+		if _, ok := observers[opts.Observer]; !ok {
+			err := fmt.Errorf("unknown observer %s; must be one of: %s",
+				opts.Observer, strings.Join(observerNames(), ", "))
+			return Result{Status: err.Error(), ExitCode: 1}, err
+		}
+	}
+	switch opts.Timestamps {
+	case "", timestampsElapsed, timestampsWall:
+	default:
+		err := fmt.Errorf("unknown timestamps %s; must be one of: %s, %s",
+			opts.Timestamps, timestampsElapsed, timestampsWall)
+		return Result{Status: err.Error(), ExitCode: 1}, err
+	}
+	if opts.Stdout == nil {
+		opts.Stdout = os.Stdout
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = os.Stderr
+	}
+	if opts.NinjaLog == "" {
+		opts.NinjaLog = ".ninja_log"
+	}
+	cfg := config{
+		TickerDuration: opts.Ticker,
+		Observe:        opts.Observer,
+		JSON:           opts.JSON.withDefaults(),
+		TimingFD:       opts.TimingFD,
+		Top:            opts.Top,
+		NinjaLog:       opts.NinjaLog,
+	}
+
+	term := newMux(opts.Stdout, opts.Stderr)
+	chroma := color.New(color.FgMagenta, color.Bold)
+	if !isTerminal(opts.Stderr) {
+		chroma.DisableColor()
+	}
+	// The transcript of the run, if requested. Created when the command starts.
+	var tr *transcript
+	out := func(format string, a ...any) {
+		// A single write, so that the mux does not split it.
+		term.Write([]byte(chroma.Sprintf(format, a...)))
+		if tr != nil {
+			tr.text(tagTimeit, fmt.Sprintf(format, a...))
+		}
+	}
+	dur100 := cfg.TickerDuration / 100
+	// Result of a failure before the command runs.
+	failed := func(format string, a ...any) (Result, error) {
+		err := fmt.Errorf(format, a...)
+		return Result{Status: err.Error(), ExitCode: 1, precision: dur100}, err
+	}
+
+	cmd := exec.CommandContext(ctx, opts.Command[0], opts.Command[1:]...)
+	cmd.Stdin = opts.Stdin
+	// Once the command is killed, do not wait forever for the output it passed to
+	// a background process (see below).
+	cmd.WaitDelay = outputGrace
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return failed("getting pipe for command stdout: %s", err)
+	}
+	// Some observers read also stderr, since their tools (for example docker build)
+	// print their progress there.
+	var stderr io.Reader
+	if stderrObservers[cfg.Observe] {
+		if stderr, err = cmd.StderrPipe(); err != nil {
+			return failed("getting pipe for command stderr: %s", err)
+		}
+	}
+
+	// The command writes timing records to the write end of this pipe. The child
+	// process gets the first of cmd.ExtraFiles as file descriptor 3.
+	var timingR, timingW *os.File
+	if cfg.TimingFD {
+		if timingR, timingW, err = os.Pipe(); err != nil {
+			return failed("creating pipe for timing records: %s", err)
+		}
+		cmd.ExtraFiles = []*os.File{timingW}
+		cmd.Env = append(os.Environ(), "TIMEIT_FD=3")
+	}
+
+	// The terminal of stderr, if any.
+	var tty *os.File
+	if isTerminal(opts.Stderr) {
+		tty = opts.Stderr.(*os.File)
+	}
+	writeNow := func(p []byte) { term.WriteNow(p) }
+
+	// Before the command starts, since the setup moves the cursor.
+	var dash *dashboard
+	if tty != nil && opts.TUI && cfg.TickerDuration > 0 {
+		dash = newDashboard(writeNow, func() (int, int, error) { return termSize(tty) })
+		if err := dash.setup(); err != nil {
+			// Fall back to the ticker.
+			dash = nil
+		}
+	}
+
+	t0 := time.Now()
+	// Where the output of the command goes.
+	stdoutW, stderrW := term.Stdout(), term.Stderr()
+	if opts.Timestamps != "" {
+		tsStdout := newTimestamper(stdoutW, opts.Timestamps, t0, muxStdout)
+		tsStderr := newTimestamper(stderrW, opts.Timestamps, t0, muxStderr)
+		if !isTerminal(opts.Stdout) {
+			tsStdout.color.DisableColor()
+		}
+		if !isTerminal(opts.Stderr) {
+			tsStderr.color.DisableColor()
+		}
+		stdoutW, stderrW = tsStdout, tsStderr
+	}
+	var trStdout, trStderr *transcriptWriter
+	if opts.Log != nil {
+		tr = newTranscript(opts.Log, t0, opts.Command)
+		trStdout, trStderr = tr.stream(tagStdout), tr.stream(tagStderr)
+		stdoutW = io.MultiWriter(stdoutW, trStdout)
+		stderrW = io.MultiWriter(stderrW, trStderr)
+	}
+	if stderr == nil {
+		// Unless it must be timestamped or logged, leave the stderr file (for
+		// example the terminal) to the command, so that its colors and progress
+		// bars do not change. Any other writer goes through term, which writes
+		// also the ticker to it.
+		cmd.Stderr = stderrW
+		if f, ok := opts.Stderr.(*os.File); ok && opts.Timestamps == "" && tr == nil {
+			cmd.Stderr = f
+		}
+	}
+
+	if err := cmd.Start(); err != nil {
+		if dash != nil {
+			dash.teardown()
+		}
+		res, err := failed("starting command: %s", err)
+		res.Real = time.Since(t0)
+		if tr != nil {
+			tr.text(tagResult, res.Report())
+			if err := tr.flush(); err != nil {
+				res.Errors = append(res.Errors, err)
+			}
+		}
+		return res, err
+	}
+	if timingW != nil {
+		// Now only the child has the write end.
+		timingW.Close()
+	}
+
+	//
+	// Here we are in the parent, after having started the child.
+	//
+
+	records := newRecords()
+	if cfg.Top > 0 {
+		records.stream = newFlightStats(cfg.Top)
+	}
+	var obs observer
+	if newObserver, ok := observers[cfg.Observe]; ok {
+		obs = newObserver(records, cfg)
+		if st, ok := obs.(starter); ok {
+			st.start(t0)
+		}
+	}
+	// Each goroutine closes its done channel when it terminates and reports its
+	// errors to errCh. The errors are printed with the results.
+	errCh := make(chan error)
+	errsDone := collectErrors(errCh)
+	outputDone := setupProcessOutput(obs, stdout, stderr, stdoutW, stderrW, errCh)
+	timingDone := setupTimingRecords(timingR, newFDObserver(records, t0), errCh)
+
+	// Closed to stop the goroutines that would run forever.
+	stop := make(chan struct{})
+	var signalDone <-chan struct{} = stop
+	if opts.handleSignals {
+		signalDone = setupSignalHandling(stop, out)
+	}
+
+	summarize := cfg.Observe != "" || cfg.TimingFD
+	var tickerDone <-chan struct{}
+	if dash != nil {
+		// The dashboard is only on the terminal: the transcript gets the ticker.
+		var log printFn
+		if tr != nil {
+			log = func(format string, a ...any) {
+				tr.text(tagTimeit, fmt.Sprintf(format, a...))
+			}
+		}
+		tickerDone = setupDashboard(stop, t0, cfg.TickerDuration, summarize, records,
+			dash, log)
+	} else {
+		tickerDone = setupPeriodicTicker(stop, t0, cfg.TickerDuration, summarize, records,
+			out)
+	}
+	var termProgressDone <-chan struct{} = stop
+	if tty != nil && opts.TermProgress {
+		tp := newTermProgress(writeNow, os.Getenv, filepath.Base(opts.Command[0]))
+		termProgressDone = setupTermProgress(stop, t0, records, tp)
+	}
+
+	// When using pipes, cmd.Wait() must be called _after_ the pipe is drained,
+	// since it closes the pipe: the last lines of the output would be lost.
+	// See https://pkg.go.dev/os/exec#Cmd.StdoutPipe
+	// If ctx is done, the command is killed, but a background process started by
+	// it could keep the pipes open: do not wait for it forever.
+	select {
+	case <-outputDone:
+	case <-ctx.Done():
+		select {
+		case <-outputDone:
+		case <-time.After(outputGrace):
+			// The readers take this as the end of the output.
+			stdout.Close()
+			if c, ok := stderr.(io.Closer); ok {
+				c.Close()
+			}
+			<-outputDone
+		}
+	}
+	waitErr := cmd.Wait()
+	elapsed := time.Since(t0)
+	// A background process started by the command could keep the timing pipe
+	// open after the command terminated: do not wait for it forever.
+	if timingR != nil {
+		timingR.SetReadDeadline(time.Now().Add(timingGrace))
+	}
+	<-timingDone
+	close(stop)
+	<-tickerDone
+	<-termProgressDone
+	<-signalDone
+	// All the output of the command has been drained: from now on, the output of
+	// timeit can be written immediately.
+	term.Close()
+
+	if fin, ok := obs.(finisher); ok {
+		if err := fin.finish(t0); err != nil {
+			errCh <- err
+		}
+	}
+	close(errCh)
+
+	msg, code := extractStatus(cmd.ProcessState, waitErr)
+	res := Result{
+		Status:    msg,
+		ExitCode:  code,
+		Real:      elapsed,
+		User:      cmd.ProcessState.UserTime(),
+		System:    cmd.ProcessState.SystemTime(),
+		Rusage:    cmd.ProcessState.SysUsage(),
+		Errors:    <-errsDone,
+		precision: dur100,
+	}
+	if summarize || cfg.Top > 0 {
+		res.records = records
+	}
+	res.Flights, res.Marks = flightsAndMarks(records)
+	if tr != nil {
+		trStdout.flush()
+		trStderr.flush()
+		tr.text(tagResult, res.Report())
+		if err := tr.flush(); err != nil {
+			res.Errors = append(res.Errors, err)
+		}
+	}
+	return res, ctx.Err()
+}
+
+// isTerminal returns true if w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && isatty.IsTerminal(f.Fd())
+}
+
+// flightsAndMarks returns the landed flights and the marks of records.
+func flightsAndMarks(records *records) ([]Flight, []Mark) {
+	landed := records.landed
+	if records.stream != nil {
+		records.stream.flush()
+		landed = records.stream.slowest.events
+		// In landing order, as without stream.
+		landed = slices.Clone(landed)
+		slices.SortStableFunc(landed, func(a, b event) int {
+			return a.finished.Compare(b.finished)
+		})
+	}
+	var flights []Flight
+	for _, evt := range landed {
+		flights = append(flights, Flight{
+			Name:     evt.name,
+			Status:   evt.status,
+			Path:     evt.path,
+			Worker:   evt.worker,
+			Attempt:  evt.attempt,
+			Started:  evt.started,
+			Finished: evt.finished,
+		})
+	}
+	var marks []Mark
+	for _, m := range records.marks {
+		marks = append(marks, Mark{Name: m.name, At: m.at})
+	}
+	return flights, marks
+}
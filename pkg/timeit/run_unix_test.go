@@ -0,0 +1,231 @@
+//go:build !windows
+
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marco-m/timeit/pkg/timeit"
+
+	"github.com/fatih/color"
+	"gotest.tools/v3/assert"
+)
+
+func TestRunCommandFailure(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	res, err := timeit.Run(context.Background(), timeit.Options{
+		Command: []string{"sh", "-c", "echo hello; echo oops >&2; exit 3"},
+		Stdout:  &stdout,
+		Stderr:  &stderr,
+	})
+
+	assert.NilError(t, err)
+	assert.Equal(t, res.ExitCode, 3)
+	assert.Equal(t, res.Status, "command failed: exit status 3")
+	assert.Assert(t, res.Real > 0)
+	assert.Equal(t, stdout.String(), "hello\n")
+	assert.Equal(t, stderr.String(), "oops\n")
+	assert.Assert(t, !strings.Contains(res.Report(), "flights by duration"))
+}
+
+func TestRunCommandNotFound(t *testing.T) {
+	res, err := timeit.Run(context.Background(), timeit.Options{
+		Command: []string{"non-existing"},
+	})
+
+	assert.ErrorContains(t, err, "starting command:")
+	assert.Equal(t, res.ExitCode, 1)
+	assert.Equal(t, res.Status, err.Error())
+}
+
+func TestRunUnknownObserver(t *testing.T) {
+	_, err := timeit.Run(context.Background(), timeit.Options{
+		Command:  []string{"true"},
+		Observer: "banana",
+	})
+
+	assert.ErrorContains(t, err, "unknown observer banana")
+}
+
+func TestRunDrainsOutput(t *testing.T) {
+	for _, observer := range []string{"", "pytest"} {
+		t.Run("observer="+observer, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+
+			res, err := timeit.Run(context.Background(), timeit.Options{
+				Command:  []string{"sh", "-c", "seq 1 50000; seq 1 50000 >&2"},
+				Stdout:   &stdout,
+				Stderr:   &stderr,
+				Ticker:   time.Hour,
+				Observer: observer,
+			})
+
+			assert.NilError(t, err)
+			assert.Equal(t, res.ExitCode, 0)
+			// All the lines, up to the last one, before Run returns.
+			assert.Equal(t, strings.Count(stdout.String(), "\n"), 50000)
+			assert.Assert(t, strings.HasSuffix(stdout.String(), "\n50000\n"))
+			assert.Assert(t, strings.HasSuffix(stderr.String(), "\n50000\n"))
+		})
+	}
+}
+
+func TestRunObservesStderrOnlyIfNeeded(t *testing.T) {
+	script := `
+echo '[gw0] [ 50%] PASSED test_a.py::test_out'
+echo '[gw0] [100%] PASSED test_a.py::test_err' >&2
+echo "make: *** [out] Error 1"
+echo "make: *** [err] Error 2" >&2
+`
+	names := func(observer string) []string {
+		var stdout, stderr bytes.Buffer
+		res, err := timeit.Run(context.Background(), timeit.Options{
+			Command:  []string{"sh", "-c", script},
+			Stdout:   &stdout,
+			Stderr:   &stderr,
+			Ticker:   time.Hour,
+			Observer: observer,
+		})
+		assert.NilError(t, err)
+		assert.Assert(t, strings.Contains(stderr.String(), "test_err"))
+		var names []string
+		for _, f := range res.Flights {
+			names = append(names, f.Name)
+		}
+		// The two streams are read concurrently.
+		sort.Strings(names)
+		return names
+	}
+
+	assert.DeepEqual(t, names("pytest"), []string{"test_a.py::test_out"})
+	assert.DeepEqual(t, names("make"), []string{"err", "out"})
+}
+
+func TestRunPassesStderrFileThrough(t *testing.T) {
+	// What the command sees as its stderr.
+	stderrKind := func(opts timeit.Options) string {
+		f, err := os.Create(filepath.Join(t.TempDir(), "stderr"))
+		assert.NilError(t, err)
+		defer f.Close()
+		var stdout bytes.Buffer
+		opts.Command = []string{"sh", "-c",
+			"if test -p /dev/stderr; then echo pipe; else echo file; fi"}
+		opts.Stdout = &stdout
+		opts.Stderr = f
+		_, err = timeit.Run(context.Background(), opts)
+		assert.NilError(t, err)
+		// The last word, after the timestamp, if any.
+		words := strings.Fields(stdout.String())
+		return words[len(words)-1]
+	}
+
+	assert.Equal(t, stderrKind(timeit.Options{}), "file")
+	assert.Equal(t, stderrKind(timeit.Options{Ticker: time.Hour}), "file")
+	assert.Equal(t, stderrKind(timeit.Options{Timestamps: "elapsed"}), "pipe")
+	assert.Equal(t, stderrKind(timeit.Options{Log: io.Discard}), "pipe")
+}
+
+func TestRunNoColorIfNotTerminal(t *testing.T) {
+	// As if Main had seen a terminal.
+	noColor := color.NoColor
+	color.NoColor = false
+	t.Cleanup(func() { color.NoColor = noColor })
+	var stdout, stderr bytes.Buffer
+
+	_, err := timeit.Run(context.Background(), timeit.Options{
+		Command:    []string{"sh", "-c", "echo hello; sleep 0.1; echo oops >&2"},
+		Stdout:     &stdout,
+		Stderr:     &stderr,
+		Ticker:     10 * time.Millisecond,
+		Timestamps: "elapsed",
+	})
+
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(stderr.String(), "timeit ticker:"), stderr.String())
+	assert.Assert(t, !strings.Contains(stdout.String(), "\x1b["), "stdout: %q", stdout.String())
+	assert.Assert(t, !strings.Contains(stderr.String(), "\x1b["), "stderr: %q", stderr.String())
+}
+
+func TestRunContextCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	res, err := timeit.Run(ctx, timeit.Options{
+		Command: []string{"sleep", "10"},
+	})
+
+	assert.Assert(t, errors.Is(err, context.DeadlineExceeded), "err: %v", err)
+	assert.Equal(t, res.ExitCode, 128+9) // SIGKILL
+	assert.Assert(t, res.Real < 5*time.Second, "real: %v", res.Real)
+}
+
+func TestRunContextCancelWithBackgroundProcess(t *testing.T) {
+	for _, observer := range []string{"", "pytest"} {
+		t.Run("observer="+observer, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			var stdout, stderr bytes.Buffer
+
+			// The background sleep keeps the stdout and stderr of the command open
+			// after the command is killed.
+			start := time.Now()
+			_, err := timeit.Run(ctx, timeit.Options{
+				Command:  []string{"sh", "-c", "echo before; sleep 3 & wait"},
+				Stdout:   &stdout,
+				Stderr:   &stderr,
+				Observer: observer,
+			})
+
+			assert.Assert(t, errors.Is(err, context.DeadlineExceeded), "err: %v", err)
+			elapsed := time.Since(start)
+			assert.Assert(t, elapsed < 2*time.Second, "elapsed: %v", elapsed)
+			assert.Equal(t, stdout.String(), "before\n")
+		})
+	}
+}
+
+func TestRunFlightsAndMarks(t *testing.T) {
+	var stdout bytes.Buffer
+	script := `
+echo 'begin apple' >&$TIMEIT_FD
+echo 'end apple ok' >&$TIMEIT_FD
+echo 'begin "fruits > banana"' >&$TIMEIT_FD
+echo 'end "fruits > banana" FAIL' >&$TIMEIT_FD
+echo 'mark ready' >&$TIMEIT_FD
+echo done
+`
+
+	res, err := timeit.Run(context.Background(), timeit.Options{
+		Command:  []string{"sh", "-c", script},
+		Stdout:   &stdout,
+		TimingFD: true,
+	})
+
+	assert.NilError(t, err)
+	assert.Equal(t, res.ExitCode, 0)
+	assert.Equal(t, stdout.String(), "done\n")
+	assert.Equal(t, len(res.Flights), 2)
+	assert.Equal(t, res.Flights[0].Name, "apple")
+	assert.Equal(t, res.Flights[0].Status, "ok")
+	assert.Equal(t, res.Flights[0].Attempt, 1)
+	assert.Assert(t, res.Flights[0].Duration() >= 0)
+	assert.Equal(t, res.Flights[1].Name, "fruits > banana")
+	assert.Equal(t, res.Flights[1].Status, "FAIL")
+	assert.DeepEqual(t, res.Flights[1].Path, []string{"fruits", "banana"})
+	assert.Equal(t, len(res.Marks), 1)
+	assert.Equal(t, res.Marks[0].Name, "ready")
+	assert.Assert(t, strings.Contains(res.Report(), "flights by duration:"))
+}
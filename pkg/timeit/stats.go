@@ -0,0 +1,175 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"container/heap"
+	"math"
+	"slices"
+	"sort"
+	"time"
+)
+
+// flightStats summarizes the landed flights in bounded memory, for runs with too
+// many flights to keep them all (see --top): the slowest flights, the number of
+// flights by status, the quantiles of the durations and the totals of the groups.
+type flightStats struct {
+	slowest  slowestFlights
+	sketch   durationSketch
+	statuses map[string]int
+	count    int
+	// The groups of the landed flights, without the flights themselves (the
+	// leaves), since the number of groups is much smaller.
+	groups *node
+	// The last landed flight, not yet added to the statistics, since an observer
+	// can still amend it (see records.amend).
+	pending *event
+}
+
+func newFlightStats(top int) *flightStats {
+	return &flightStats{
+		slowest:  slowestFlights{k: top},
+		sketch:   durationSketch{buckets: make(map[int]int)},
+		statuses: make(map[string]int),
+		groups:   &node{},
+	}
+}
+
+// add adds evt to the statistics.
+func (st *flightStats) add(evt event) {
+	st.flush()
+	st.pending = &evt
+}
+
+// flush adds the pending flight, if any, to the statistics.
+func (st *flightStats) flush() {
+	if st.pending == nil {
+		return
+	}
+	evt := *st.pending
+	st.pending = nil
+
+	st.count++
+	st.statuses[evt.status]++
+	st.sketch.add(evt.duration())
+	st.slowest.add(evt)
+	if len(evt.path) > 1 {
+		n := st.groups
+		n.update(&evt)
+		for _, label := range evt.path[:len(evt.path)-1] {
+			n = n.child(label)
+			n.update(&evt)
+		}
+	}
+}
+
+// byStatus returns the statuses of the landed flights, the most frequent first.
+func (st *flightStats) byStatus() []string {
+	statuses := make([]string, 0, len(st.statuses))
+	for status := range st.statuses {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if st.statuses[statuses[i]] != st.statuses[statuses[j]] {
+			return st.statuses[statuses[i]] > st.statuses[statuses[j]]
+		}
+		return statuses[i] < statuses[j]
+	})
+	return statuses
+}
+
+// quantiles are the quantiles of the durations shown in the results.
+var quantiles = []struct {
+	name string
+	q    float64
+}{{"p50", 0.50}, {"p90", 0.90}, {"p99", 0.99}}
+
+// slowestFlights keeps the k slowest of the flights added to it, as a min-heap
+// by duration (see container/heap).
+type slowestFlights struct {
+	k      int
+	events []event
+}
+
+func (s *slowestFlights) add(evt event) {
+	if len(s.events) < s.k {
+		heap.Push(s, evt)
+		return
+	}
+	if evt.duration() > s.events[0].duration() {
+		s.events[0] = evt
+		heap.Fix(s, 0)
+	}
+}
+
+func (s *slowestFlights) Len() int { return len(s.events) }
+
+func (s *slowestFlights) Less(i, j int) bool {
+	return s.events[i].duration() < s.events[j].duration()
+}
+
+func (s *slowestFlights) Swap(i, j int) { s.events[i], s.events[j] = s.events[j], s.events[i] }
+
+func (s *slowestFlights) Push(x any) { s.events = append(s.events, x.(event)) }
+
+func (s *slowestFlights) Pop() any {
+	evt := s.events[len(s.events)-1]
+	s.events = s.events[:len(s.events)-1]
+	return evt
+}
+
+// sketchAccuracy is the maximum relative error of the quantiles estimated by a
+// durationSketch.
+const sketchAccuracy = 0.01
+
+var sketchGamma = (1 + sketchAccuracy) / (1 - sketchAccuracy)
+
+// durationSketch estimates the quantiles of the durations added to it, with
+// relative error at most sketchAccuracy. It counts the durations in buckets of
+// exponentially increasing width (as DDSketch does), so its size depends on the
+// range of the durations, not on their number: less than 2000 buckets from 1ns
+// to one day.
+type durationSketch struct {
+	// Bucket i counts the durations in (gamma^(i-1), gamma^i] nanoseconds.
+	buckets map[int]int
+	// Number of durations <= 0.
+	zero  int
+	count int
+}
+
+func (s *durationSketch) add(d time.Duration) {
+	s.count++
+	if d <= 0 {
+		s.zero++
+		return
+	}
+	s.buckets[int(math.Ceil(math.Log(float64(d))/math.Log(sketchGamma)))]++
+}
+
+// quantile returns an estimate of the q-quantile (0 <= q <= 1) of the durations,
+// or zero if there are none.
+func (s *durationSketch) quantile(q float64) time.Duration {
+	if s.count == 0 {
+		return 0
+	}
+	rank := int(q * float64(s.count-1))
+	seen := s.zero
+	if rank < seen {
+		return 0
+	}
+	keys := make([]int, 0, len(s.buckets))
+	for k := range s.buckets {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		seen += s.buckets[k]
+		if seen > rank {
+			// The value with the same relative error from both ends of the bucket.
+			return time.Duration(2 * math.Pow(sketchGamma, float64(k)) / (sketchGamma + 1))
+		}
+	}
+	// Not reached.
+	return 0
+}
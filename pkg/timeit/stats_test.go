@@ -0,0 +1,92 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+func TestSlowestFlights(t *testing.T) {
+	t0 := time.Now()
+	slowest := slowestFlights{k: 3}
+	for _, secs := range []int{5, 1, 7, 3, 9, 2, 8} {
+		slowest.add(event{
+			name:     fmt.Sprintf("test_%d", secs),
+			started:  t0,
+			finished: t0.Add(time.Duration(secs) * time.Second),
+		})
+	}
+
+	assert.Check(t, cmp.DeepEqual(durations(slowest.events), map[string]string{
+		"test_7": "7s ",
+		"test_8": "8s ",
+		"test_9": "9s ",
+	}))
+}
+
+func TestDurationSketch(t *testing.T) {
+	sketch := durationSketch{buckets: make(map[int]int)}
+	// 1ms, 2ms, ..., 10s.
+	for i := 1; i <= 10_000; i++ {
+		sketch.add(time.Duration(i) * time.Millisecond)
+	}
+
+	for _, tc := range []struct {
+		q    float64
+		want time.Duration
+	}{
+		{0, 1 * time.Millisecond},
+		{0.5, 5 * time.Second},
+		{0.9, 9 * time.Second},
+		{0.99, 9900 * time.Millisecond},
+		{1, 10 * time.Second},
+	} {
+		got := sketch.quantile(tc.q)
+		relErr := math.Abs(float64(got-tc.want)) / float64(tc.want)
+		assert.Check(t, relErr <= sketchAccuracy, "q=%v: got %v, want %v", tc.q, got, tc.want)
+	}
+	assert.Check(t, len(sketch.buckets) < 500, "buckets: %d", len(sketch.buckets))
+}
+
+func TestDurationSketchEmpty(t *testing.T) {
+	sketch := durationSketch{buckets: make(map[int]int)}
+	assert.Equal(t, sketch.quantile(0.5), time.Duration(0))
+}
+
+func TestRecordsStream(t *testing.T) {
+	t0 := time.Now()
+	records := newRecords()
+	records.stream = newFlightStats(2)
+	for i, secs := range []int{1, 4, 2, 3} {
+		name := fmt.Sprintf("test_%d", i)
+		records.takeoff(name, t0, "file.py", name)
+		records.land(name, "ok", t0.Add(time.Duration(secs)*time.Second), 0)
+	}
+	// Amends the last landed flight, test_3, from 3s to 5s.
+	records.amend("test_3", "not ok", 5*time.Second)
+	records.stream.flush()
+
+	assert.Check(t, cmp.Len(records.landed, 0))
+	assert.Check(t, cmp.Len(records.attempts, 0))
+	assert.Check(t, cmp.DeepEqual(durations(records.stream.slowest.events), map[string]string{
+		"test_1": "4s ok",
+		"test_3": "5s not ok",
+	}))
+	assert.Equal(t, records.stream.count, 4)
+	assert.DeepEqual(t, records.stream.statuses, map[string]int{"ok": 3, "not ok": 1})
+	assert.DeepEqual(t, records.stream.byStatus(), []string{"ok", "not ok"})
+
+	group := records.stream.groups.byLabel["file.py"]
+	assert.Assert(t, group != nil)
+	assert.Equal(t, group.count, 4)
+	// From t0-2s (test_3, amended) to t0+4s (test_1).
+	assert.Equal(t, group.duration(time.Time{}), 6*time.Second)
+	assert.Check(t, cmp.Len(group.children, 0))
+}
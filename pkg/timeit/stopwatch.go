@@ -0,0 +1,158 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// StopwatchOptions configures NewStopwatch.
+type StopwatchOptions struct {
+	// If not zero, write the ticker, with the spans in flight, to Out each Ticker.
+	Ticker time.Duration
+	// Where to write the ticker. If nil, os.Stderr.
+	Out io.Writer
+	// If not zero, keep only the Top slowest spans (see Options.Top).
+	Top int
+}
+
+// A Stopwatch times the spans of a Go program, as Run times the flights of a
+// command: it writes the ticker with the spans in flight and returns the same
+// results. It is safe for concurrent use.
+//
+//	sw := timeit.NewStopwatch(timeit.StopwatchOptions{Ticker: time.Minute})
+//	span := sw.Start("load")
+//	...
+//	span.End("ok")
+//	res := sw.Stop()
+//	fmt.Fprint(os.Stderr, res.Report())
+type Stopwatch struct {
+	records    *records
+	t0         time.Time
+	precision  time.Duration
+	stop       chan struct{}
+	tickerDone <-chan struct{}
+	// Held for reading to change records, for writing to stop.
+	mu      sync.RWMutex
+	stopped bool
+	elapsed time.Duration
+}
+
+// A Span is an operation timed by a Stopwatch. Call End once the operation is
+// over.
+type Span struct {
+	sw  *Stopwatch
+	key flightKey
+	// The labels of the span and of its parents, outermost first.
+	labels []string
+}
+
+// NewStopwatch starts a Stopwatch, and its ticker if requested. Call Stop to stop
+// it.
+func NewStopwatch(opts StopwatchOptions) *Stopwatch {
+	if opts.Out == nil {
+		opts.Out = os.Stderr
+	}
+	chroma := color.New(color.FgMagenta, color.Bold)
+	out := func(format string, a ...any) {
+		io.WriteString(opts.Out, chroma.Sprintf(format, a...))
+	}
+
+	sw := &Stopwatch{
+		records:   newRecords(),
+		t0:        time.Now(),
+		precision: opts.Ticker / 100,
+		stop:      make(chan struct{}),
+	}
+	if opts.Top > 0 {
+		sw.records.stream = newFlightStats(opts.Top)
+	}
+	sw.tickerDone = setupPeriodicTicker(sw.stop, sw.t0, opts.Ticker, true, sw.records, out)
+	return sw
+}
+
+// Start starts span name.
+func (sw *Stopwatch) Start(name string) *Span {
+	return sw.start([]string{name})
+}
+
+func (sw *Stopwatch) start(labels []string) *Span {
+	name := strings.Join(labels, sectionSep)
+	var path []string
+	if len(labels) > 1 {
+		path = labels
+	}
+	sp := &Span{sw: sw, labels: labels}
+	sw.mu.RLock()
+	defer sw.mu.RUnlock()
+	if !sw.stopped {
+		sp.key = sw.records.takeoffOn("", name, time.Now(), path...)
+	}
+	return sp
+}
+
+// Start starts span name, nested in sp. Its name is the path from the outermost
+// span, for example "load > parse".
+func (sp *Span) Start(name string) *Span {
+	return sp.sw.start(append(sp.labels[:len(sp.labels):len(sp.labels)], name))
+}
+
+// End ends sp, with status (for example "ok", or empty). Ending a span again has
+// no effect.
+func (sp *Span) End(status string) {
+	sw := sp.sw
+	sw.mu.RLock()
+	defer sw.mu.RUnlock()
+	if !sw.stopped {
+		sw.records.landKey(sp.key, status, time.Now())
+	}
+}
+
+// Mark records point in time name.
+func (sw *Stopwatch) Mark(name string) {
+	sw.mu.RLock()
+	defer sw.mu.RUnlock()
+	if !sw.stopped {
+		sw.records.addMark(name, time.Since(sw.t0))
+	}
+}
+
+// Progress records that done out of total operations are over, for the progress
+// and the estimated time of arrival shown by the ticker.
+func (sw *Stopwatch) Progress(done int, total int) {
+	sw.mu.RLock()
+	defer sw.mu.RUnlock()
+	if !sw.stopped {
+		sw.records.setProgress(done, total)
+	}
+}
+
+// Stop stops sw and its ticker and returns the ended spans, as Result.Flights.
+// The spans still in flight, and the calls after Stop, are not part of the
+// result. Calling Stop again returns the same result.
+func (sw *Stopwatch) Stop() Result {
+	sw.mu.Lock()
+	if !sw.stopped {
+		sw.stopped = true
+		sw.elapsed = time.Since(sw.t0)
+		close(sw.stop)
+	}
+	sw.mu.Unlock()
+	<-sw.tickerDone
+
+	res := Result{
+		Status:    "stopwatch stopped",
+		Real:      sw.elapsed,
+		records:   sw.records,
+		precision: sw.precision,
+	}
+	res.Flights, res.Marks = flightsAndMarks(sw.records)
+	return res
+}
@@ -0,0 +1,76 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/poll"
+)
+
+func TestStopwatchSpans(t *testing.T) {
+	sw := NewStopwatch(StopwatchOptions{})
+
+	load := sw.Start("load")
+	parse := load.Start("parse")
+	// Same name, in flight at the same time.
+	fetch1 := sw.Start("fetch")
+	fetch2 := sw.Start("fetch")
+	fetch2.End("ok")
+	fetch1.End("FAIL")
+	parse.End("ok")
+	parse.End("ignored")
+	sw.Mark("loaded")
+	load.End("")
+	sw.Start("never ended")
+
+	res := sw.Stop()
+	sw.Start("after stop").End("ok")
+
+	assert.Equal(t, res.Status, "stopwatch stopped")
+	assert.Equal(t, len(res.Flights), 4)
+	assert.Equal(t, res.Flights[0].Name, "fetch")
+	assert.Equal(t, res.Flights[0].Attempt, 2)
+	assert.Equal(t, res.Flights[0].Status, "ok")
+	assert.Equal(t, res.Flights[1].Name, "fetch")
+	assert.Equal(t, res.Flights[1].Attempt, 1)
+	assert.Equal(t, res.Flights[1].Status, "FAIL")
+	assert.Equal(t, res.Flights[2].Name, "load > parse")
+	assert.DeepEqual(t, res.Flights[2].Path, []string{"load", "parse"})
+	assert.Equal(t, res.Flights[2].Status, "ok")
+	assert.Equal(t, res.Flights[3].Name, "load")
+	assert.Equal(t, len(res.Flights[3].Path), 0)
+	assert.Equal(t, len(res.Marks), 1)
+	assert.Equal(t, res.Marks[0].Name, "loaded")
+
+	report := res.Report()
+	assert.Assert(t, strings.Contains(report, "stopwatch stopped"), report)
+	assert.Assert(t, strings.Contains(report, "flights by duration:"), report)
+	assert.Assert(t, strings.Contains(report, "fetch (attempt 2)"), report)
+	assert.Assert(t, !strings.Contains(report, "never ended"), report)
+	assert.Assert(t, !strings.Contains(report, "after stop"), report)
+}
+
+func TestStopwatchTicker(t *testing.T) {
+	var out syncBuffer
+	sw := NewStopwatch(StopwatchOptions{Ticker: 10 * time.Millisecond, Out: &out})
+	span := sw.Start("download")
+	sw.Progress(1, 4)
+
+	poll.WaitOn(t, func(poll.LogT) poll.Result {
+		s := out.String()
+		if strings.Contains(s, "in-flight:") && strings.Contains(s, "download") &&
+			strings.Contains(s, "progress: 1/4") {
+			return poll.Success()
+		}
+		return poll.Continue("got %q", s)
+	}, poll.WithTimeout(time.Second), poll.WithDelay(5*time.Millisecond))
+
+	span.End("ok")
+	res := sw.Stop()
+	assert.Equal(t, len(res.Flights), 1)
+}
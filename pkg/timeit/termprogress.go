@@ -0,0 +1,159 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// termProgressInterval is how often the terminal progress is updated.
+const termProgressInterval = time.Second
+
+// A termProgress shows the progress of the run outside of the terminal contents,
+// so that it is visible also from a background tab (see --term-progress): in the
+// window title and, if the terminal supports it, as OSC 9;4 progress (the tab
+// progress bar of ConEmu, Windows Terminal, WezTerm, Ghostty).
+type termProgress struct {
+	// write writes to the terminal, atomically with respect to the output of the
+	// command (see mux.WriteNow).
+	write func(p []byte)
+	// True if the terminal supports OSC 9;4.
+	osc94 bool
+	// The name of the command, for the title.
+	command string
+}
+
+// Escape sequences of the terminal progress.
+const (
+	// OSC 9;4;STATE;PERCENT ST. STATE 0: remove, 1: normal, 2: error,
+	// 3: indeterminate.
+	escProgress = "\x1b]9;4;%d;%d\x1b\\"
+	// OSC 0;TITLE BEL: set the window and tab title.
+	escSetTitle = "\x1b]0;%s\a"
+	// Save and restore the title (xterm window manipulation, ignored by the
+	// terminals that do not support it).
+	escPushTitle = "\x1b[22;0t"
+	escPopTitle  = "\x1b[23;0t"
+)
+
+const (
+	progressRemove = iota
+	progressNormal
+	progressError
+	progressIndeterminate
+)
+
+func newTermProgress(write func(p []byte), getenv func(string) string,
+	command string,
+) *termProgress {
+	return &termProgress{write: write, osc94: supportsOSC94(getenv), command: command}
+}
+
+// supportsOSC94 returns true if the terminal, as described by the environment,
+// supports the OSC 9;4 progress sequence. Other terminals could show it as text,
+// or as a notification (OSC 9 in iTerm2).
+func supportsOSC94(getenv func(string) string) bool {
+	switch {
+	case getenv("WT_SESSION") != "": // Windows Terminal
+		return true
+	case getenv("ConEmuANSI") == "ON":
+		return true
+	case getenv("TERM_PROGRAM") == "WezTerm", getenv("TERM_PROGRAM") == "ghostty":
+		return true
+	case getenv("TERM") == "xterm-ghostty":
+		return true
+	}
+	return false
+}
+
+// start saves the title of the terminal, restored by stop.
+func (tp *termProgress) start() {
+	tp.write([]byte(escPushTitle))
+}
+
+// update shows elapsed and, if total is not zero, the percentage of done out of
+// total. The progress is in error state if failed is not zero.
+func (tp *termProgress) update(elapsed time.Duration, done int, total int, failed int) {
+	var bld strings.Builder
+	title := fmt.Sprintf("timeit %s", elapsed.Truncate(time.Second))
+	percent := 0
+	if total > 0 {
+		percent = min(done*100/total, 100)
+		title += fmt.Sprintf(" %d%%", percent)
+	}
+	if failed > 0 {
+		title += fmt.Sprintf(" %d failed", failed)
+	}
+	title += " " + tp.command
+	fmt.Fprintf(&bld, escSetTitle, sanitizeTitle(title))
+
+	if tp.osc94 {
+		state := progressIndeterminate
+		if total > 0 {
+			state = progressNormal
+		}
+		if failed > 0 {
+			state = progressError
+		}
+		fmt.Fprintf(&bld, escProgress, state, percent)
+	}
+	tp.write([]byte(bld.String()))
+}
+
+// stop removes the progress and restores the title.
+func (tp *termProgress) stop() {
+	var bld strings.Builder
+	if tp.osc94 {
+		fmt.Fprintf(&bld, escProgress, progressRemove, 0)
+	}
+	bld.WriteString(escPopTitle)
+	tp.write([]byte(bld.String()))
+}
+
+// sanitizeTitle returns title without control characters, that would terminate
+// the escape sequence.
+func sanitizeTitle(title string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, title)
+}
+
+// setupTermProgress updates tp each termProgressInterval, until stop is closed.
+// It then stops tp and closes the returned channel.
+func setupTermProgress(stop <-chan struct{}, t0 time.Time, records *records,
+	tp *termProgress,
+) <-chan struct{} {
+	done := make(chan struct{})
+	ticker := time.NewTicker(termProgressInterval)
+	update := func(now time.Time) {
+		records.mu.Lock()
+		landedN, totalN, failedN := records.done, records.total, records.failed
+		records.mu.Unlock()
+		tp.update(now.Sub(t0), landedN, totalN, failedN)
+	}
+
+	go func() {
+		defer close(done)
+		tp.start()
+		update(time.Now())
+		for {
+			select {
+			case <-stop:
+				ticker.Stop()
+				tp.stop()
+				return
+			case now := <-ticker.C:
+				update(now)
+			}
+		}
+	}()
+
+	return done
+}
@@ -0,0 +1,69 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestSupportsOSC94(t *testing.T) {
+	testCases := []struct {
+		name string
+		env  map[string]string
+		want bool
+	}{
+		{name: "unknown terminal", env: map[string]string{"TERM": "xterm-256color"}},
+		{name: "iTerm2", env: map[string]string{"TERM_PROGRAM": "iTerm.app"}},
+		{name: "Windows Terminal", env: map[string]string{"WT_SESSION": "x"}, want: true},
+		{name: "ConEmu", env: map[string]string{"ConEmuANSI": "ON"}, want: true},
+		{name: "WezTerm", env: map[string]string{"TERM_PROGRAM": "WezTerm"}, want: true},
+		{name: "Ghostty", env: map[string]string{"TERM": "xterm-ghostty"}, want: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			getenv := func(key string) string { return tc.env[key] }
+			assert.Equal(t, supportsOSC94(getenv), tc.want)
+		})
+	}
+}
+
+func TestTermProgress(t *testing.T) {
+	var term strings.Builder
+	write := func(p []byte) { term.Write(p) }
+	getenv := func(key string) string {
+		if key == "WT_SESSION" {
+			return "x"
+		}
+		return ""
+	}
+	tp := newTermProgress(write, getenv, "pytest")
+
+	tp.start()
+	tp.update(1500*time.Millisecond, 0, 0, 0)
+	tp.update(90*time.Second, 1, 4, 0)
+	tp.update(91*time.Second, 3, 4, 1)
+	tp.stop()
+
+	assert.Equal(t, term.String(), "\x1b[22;0t"+
+		"\x1b]0;timeit 1s pytest\a"+"\x1b]9;4;3;0\x1b\\"+
+		"\x1b]0;timeit 1m30s 25% pytest\a"+"\x1b]9;4;1;25\x1b\\"+
+		"\x1b]0;timeit 1m31s 75% 1 failed pytest\a"+"\x1b]9;4;2;75\x1b\\"+
+		"\x1b]9;4;0;0\x1b\\"+"\x1b[23;0t")
+}
+
+func TestTermProgressTitleOnly(t *testing.T) {
+	var term strings.Builder
+	write := func(p []byte) { term.Write(p) }
+	getenv := func(string) string { return "" }
+	tp := newTermProgress(write, getenv, "make\x1b]0;evil\a")
+
+	tp.update(2*time.Second, 0, 0, 0)
+	tp.stop()
+
+	assert.Equal(t, term.String(), "\x1b]0;timeit 2s make]0;evil\a"+"\x1b[23;0t")
+}
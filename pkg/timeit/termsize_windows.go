@@ -0,0 +1,14 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"errors"
+	"os"
+)
+
+// termSize returns the number of rows and columns of terminal f.
+func termSize(f *os.File) (int, int, error) {
+	return 0, 0, errors.New("terminal size: not supported on Windows")
+}
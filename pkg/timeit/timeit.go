@@ -4,11 +4,13 @@ package timeit
 // Copyright (c) 2020-23 Marco Molteni and the timeit contributors.
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"os/signal"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -33,7 +35,15 @@ type config struct {
 	CheckVersion   bool          `help:"Check online if new version is available and exit."`
 	NoColor        bool          `help:"Disable color output."`
 	TickerDuration time.Duration `name:"ticker" placeholder:"DURATION" help:"Print a status line each DURATION."`
-	Observe        string        `placeholder:"FORMAT" help:"observe the output according to FORMAT and print a summary on each ticker. Supported formats: pytest."`
+	Observe        string        `placeholder:"FORMAT" help:"observe the output according to FORMAT and print a summary on each ticker. Supported formats: ansible, buildkit, ctest, json, libtest, make, ninja, pytest, sections, tap."`
+	JSON           JSONOptions   `embed:"" prefix:"json-" group:"--observe=json"`
+	TimingFD       bool          `name:"timing-fd" help:"Pass to the command, in environment variable TIMEIT_FD, a file descriptor where it can write timing records (see README)."`
+	Top            int           `placeholder:"N" help:"Keep in memory only the N slowest flights, summarizing the others in counts by status and duration quantiles, for runs with a huge number of flights (default: keep all)."`
+	TUI            bool          `name:"tui" help:"If stderr is a terminal, redraw the ticker in place as a dashboard at the bottom of the terminal, with the output of the command scrolling above it."`
+	TermProgress   bool          `name:"term-progress" help:"If stderr is a terminal, show the elapsed time and the progress in the window title and, if the terminal supports it, in the tab (OSC 9;4: ConEmu, Windows Terminal, WezTerm, Ghostty)."`
+	Timestamps     string        `enum:"none,elapsed,wall" default:"none" placeholder:"MODE" help:"Prefix each line of the command output with its time: none, elapsed (since the start) or wall (clock). Lines of stdout are tagged with |, lines of stderr with ! (default: ${default})."`
+	Log            string        `placeholder:"FILE" help:"Write to FILE a transcript of the run: the output of the command, the ticker and the results, each line with its time (see README)."`
+	NinjaLog       string        `default:".ninja_log" placeholder:"FILE" group:"--observe=ninja" help:"Read the durations of the build edges from FILE, if it exists (default: ${default})."`
 
 	// Command must be optional to support --version
 	Command []string `arg:"" optional:"" passthrough:"" help:"Command to time."`
@@ -42,10 +52,20 @@ type config struct {
 type printFn func(format string, a ...any)
 
 func Main() int {
+	// To time a command named replay or analyze, run it by its path (./replay).
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "replay":
+			return replayMain(os.Args[2:])
+		case "analyze":
+			return analyzeMain(os.Args[2:])
+		}
+	}
+
 	var cfg config
 	kong.Parse(&cfg,
 		kong.Name("timeit"),
-		kong.Description("The timeit utility measures the time of command execution."),
+		kong.Description("The timeit utility measures the time of command execution. To replay a recorded run, see timeit replay --help; to analyze a pytest log, see timeit analyze --help."),
 		kong.UsageOnError(),
 		kong.ConfigureHelp(kong.HelpOptions{
 			Compact: false,
@@ -73,9 +93,10 @@ func Main() int {
 	}
 
 	if cfg.Observe != "" {
-		if cfg.Observe != "pytest" {
+		if _, ok := observers[cfg.Observe]; !ok {
 			fmt.Fprintf(os.Stderr,
-				"timeit: unknown --observe=%s; must be pytest\n", cfg.Observe)
+				"timeit: unknown --observe=%s; must be one of: %s\n",
+				cfg.Observe, strings.Join(observerNames(), ", "))
 			return 1
 		}
 	}
@@ -84,23 +105,52 @@ func Main() int {
 			"timeit: --observe requires --ticker\n")
 		return 1
 	}
+	if cfg.TUI && cfg.TickerDuration == 0 {
+		fmt.Fprintf(os.Stderr,
+			"timeit: --tui requires --ticker\n")
+		return 1
+	}
 
 	if !isatty.IsTerminal(os.Stderr.Fd()) || cfg.NoColor {
 		color.NoColor = true
 	}
 
-	cmd := cfg.Command[0]
-	var args []string
-	if len(cfg.Command) > 1 {
-		args = cfg.Command[1:]
+	opts := Options{
+		Command:       cfg.Command,
+		Stdin:         os.Stdin,
+		Ticker:        cfg.TickerDuration,
+		Observer:      cfg.Observe,
+		JSON:          cfg.JSON,
+		NinjaLog:      cfg.NinjaLog,
+		TimingFD:      cfg.TimingFD,
+		Top:           cfg.Top,
+		TUI:           cfg.TUI,
+		TermProgress:  cfg.TermProgress,
+		Timestamps:    cfg.Timestamps,
+		handleSignals: true,
 	}
-
-	chroma := color.New(color.FgMagenta, color.Bold)
-	out := func(format string, a ...any) {
-		chroma.Fprintf(os.Stderr, format, a...)
+	if opts.Timestamps == "none" {
+		opts.Timestamps = ""
+	}
+	var logFile *os.File
+	if cfg.Log != "" {
+		var err error
+		if logFile, err = os.Create(cfg.Log); err != nil {
+			fmt.Fprintf(os.Stderr, "timeit: %s\n", err)
+			return 1
+		}
+		opts.Log = logFile
 	}
 
-	return run(cmd, args, cfg, out)
+	res, _ := Run(context.Background(), opts)
+	if logFile != nil {
+		if err := logFile.Close(); err != nil {
+			res.Errors = append(res.Errors, fmt.Errorf("writing log: %s", err))
+		}
+	}
+	// The error, if any, is also in res.Status.
+	color.New(color.FgMagenta, color.Bold).Fprint(os.Stderr, res.Report())
+	return res.ExitCode
 }
 
 func checkVersion() error {
@@ -130,73 +180,9 @@ func checkVersion() error {
 	return nil
 }
 
-type event struct {
-	name     string
-	started  time.Time
-	finished time.Time
-}
-
-type records struct {
-	mu sync.Mutex
-	// Event name -> event data
-	flying map[string]event
-	// Event name -> event data
-	landed map[string]event
-}
-
-func newRecords() *records {
-	return &records{
-		flying: make(map[string]event, 100),
-		landed: make(map[string]event, 100),
-	}
-}
-
-// Run executable (name, args) and wait for it to terminate.
-// Write our output to `out`, while the command output goes to stdout and stderr as usual.
-// Return the status code of the terminated executable.
-func run(name string, args []string, cfg config, out printFn) int {
-	dur100 := cfg.TickerDuration / 100
-	cmd := exec.Command(name, args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stderr = os.Stderr
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		var elapsed time.Duration = 0
-		out(results(fmt.Sprintf("getting pipe for command stdout: %s", err), elapsed, dur100, nil))
-		return 1
-	}
-
-	t0 := time.Now()
-	if err := cmd.Start(); err != nil {
-		elapsed := time.Since(t0)
-		out(results(fmt.Sprintf("starting command: %s", err), elapsed, dur100, nil))
-		return 1
-	}
-
-	//
-	// Here we are in the parent, after having started the child.
-	//
-
-	records := newRecords()
-	setupProcessOutput(cfg.Observe, records, stdout, out)
-
-	setupSignalHandling(out)
-
-	cancelTicker := setupPeriodicTicker(t0, cfg.TickerDuration, cfg.Observe != "", records, out)
-
-	// When using pipes, cmd.Wait() must be called _after_ the pipe is drained.
-	// See https://pkg.go.dev/os/exec#Cmd.StdoutPipe
-	// <-done FIXME
-	waitErr := cmd.Wait()
-	elapsed := time.Since(t0)
-	cancelTicker()
-
-	msg, code := extractStatus(cmd.ProcessState, waitErr)
-	out("%s", results(msg, elapsed, dur100, records))
-	return code
-}
-
-func results(msg string, elapsed time.Duration, precision time.Duration, records *records) string {
+func results(msg string, elapsed time.Duration, precision time.Duration, records *records,
+	errs []error,
+) string {
 	var bld strings.Builder
 
 	fmt.Fprintf(&bld, `
@@ -205,119 +191,359 @@ timeit results:
     real: %s
 `, msg, elapsed.Round(time.Millisecond))
 
+	if len(errs) > 0 {
+		fmt.Fprintf(&bld, "    timeit errors:\n")
+		for i, err := range errs {
+			fmt.Fprintf(&bld, "    %4d  %s\n", i+1, err)
+		}
+	}
+
 	if records != nil {
-		fmt.Fprintf(&bld, "    flights by duration:\n")
 		tw := tabwriter.NewWriter(&bld, 5, 0, 2, ' ', 0)
-		landed := make([]event, 0, len(records.landed))
+		// Copy, so that we can sort by duration and keep the landing order.
+		landed := slices.Clone(records.landed)
+		if records.stream != nil {
+			records.stream.flush()
+			landed = slices.Clone(records.stream.slowest.events)
+		}
 
-		// From map to slice, so that we can sort by duration.
-		for _, evt := range records.landed {
-			landed = append(landed, evt)
+		if records.stream != nil && records.stream.count > len(landed) {
+			fmt.Fprintf(&bld, "    flights by duration (slowest %d of %d):\n",
+				len(landed), records.stream.count)
+		} else {
+			fmt.Fprintf(&bld, "    flights by duration:\n")
 		}
 
-		sort.Slice(landed, func(i, j int) bool {
+		sort.SliceStable(landed, func(i, j int) bool {
 			elapsedI := landed[i].finished.Sub(landed[i].started)
 			elapsedJ := landed[j].finished.Sub(landed[j].started)
 			return elapsedI > elapsedJ
 		})
 		for i, evt := range landed {
 			elapsed := evt.finished.Sub(evt.started).Truncate(precision)
-			fmt.Fprintf(tw, "    %4d\t%s\t%8v\n", i+1, evt.name, elapsed)
+			fmt.Fprintf(tw, "    %4d\t%s\t%8v", i+1, evt.displayName(), elapsed)
+			if evt.status != "" {
+				fmt.Fprintf(tw, "\t%s", evt.status)
+			}
+			fmt.Fprintf(tw, "\n")
 		}
 		tw.Flush()
+
+		var root *node
+		if records.stream != nil {
+			root = records.stream.groups
+		} else if hierarchical(landed) {
+			root = newTree(landed)
+		}
+		if root != nil && len(root.children) > 0 {
+			fmt.Fprintf(&bld, "    flights by group:\n")
+			writeGroups(tw, root, 0, precision)
+			tw.Flush()
+		}
+
+		if st := records.stream; st != nil && st.count > 0 {
+			fmt.Fprintf(&bld, "    flights by status:\n")
+			for _, status := range st.byStatus() {
+				label := status
+				if label == "" {
+					label = "(none)"
+				}
+				fmt.Fprintf(tw, "    %8d\t%s\n", st.statuses[status], label)
+			}
+			tw.Flush()
+			fmt.Fprintf(&bld, "    flight duration quantiles:\n")
+			for _, q := range quantiles {
+				fmt.Fprintf(tw, "    %8s\t%8v\n", q.name,
+					st.sketch.quantile(q.q).Truncate(precision))
+			}
+			tw.Flush()
+		}
+
+		// When streaming, the attempts of the landed flights are forgotten.
+		if retried := retriedFlights(records); len(retried) > 0 && records.stream == nil {
+			fmt.Fprintf(&bld, "    flights with more than one attempt:\n")
+			for i, name := range retried {
+				statuses := make([]string, 0, records.attempts[name])
+				for _, evt := range records.landed {
+					if evt.name == name {
+						statuses = append(statuses, evt.status)
+					}
+				}
+				fmt.Fprintf(tw, "    %4d\t%s\t%s\t%s\n", i+1, name,
+					plural(records.attempts[name], "attempt"), strings.Join(statuses, " "))
+			}
+			tw.Flush()
+		}
+
+		if len(records.crashes) > 0 {
+			fmt.Fprintf(&bld, "    crashed workers:\n")
+			for i, crash := range records.crashes {
+				fmt.Fprintf(tw, "    %4d\t%s\t%s\t%s\n", i+1, crash.worker, crash.reason,
+					strings.Join(crash.flights, ", "))
+			}
+			tw.Flush()
+		}
+
+		if len(records.marks) > 0 {
+			fmt.Fprintf(&bld, "    marks:\n")
+			for i, mark := range records.marks {
+				fmt.Fprintf(tw, "    %4d\t%s\t%8v\n", i+1, mark.name,
+					mark.at.Truncate(precision))
+			}
+			tw.Flush()
+		}
 	}
 
 	return bld.String()
 }
 
-// FIXME done channel !!! ALL methods...
-// FIXME add also documentation...
-func setupProcessOutput(observe string, events *records, stdout io.Reader, out printFn) {
-	switch observe {
-	case "pytest":
-		go observePytest(events, stdout, out)
+// collectErrors collects the errors sent to errCh. Once errCh is closed, the
+// returned channel delivers them.
+func collectErrors(errCh <-chan error) <-chan []error {
+	errsDone := make(chan []error, 1)
+	go func() {
+		var errs []error
+		for err := range errCh {
+			errs = append(errs, err)
+		}
+		errsDone <- errs
+	}()
+	return errsDone
+}
+
+// setupProcessOutput copies stdout and, if not nil, stderr of the command to
+// stdoutW and stderrW, feeding obs (if not nil). Errors are sent to errCh.
+// The returned channel is closed when stdout (and stderr, if not nil) has been
+// drained.
+func setupProcessOutput(obs observer, stdout io.Reader, stderr io.Reader,
+	stdoutW io.Writer, stderrW io.Writer, errCh chan<- error,
+) <-chan struct{} {
+	done := make(chan struct{})
+	switch {
+	case obs != nil:
+		obs := &lockedObserver{obs: obs}
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := observeOutput(obs, "stdout", stdout, stdoutW); err != nil {
+				errCh <- err
+			}
+		}()
+		// Only for the observers that read also stderr (see stderrObservers).
+		if stderr != nil {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := observeOutput(obs, "stderr", stderr, stderrW); err != nil {
+					errCh <- err
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
 
-	// Simple stdout copier if --observe flag is missing or unknown.
+	// Simple stdout copier if --observe flag is missing.
 	default:
 		go func() {
-			if _, err := io.Copy(os.Stdout, stdout); err != nil {
-				// FIXME Report to the errors channel and be printed at the end.
-				out("timeit: copying stdout: %s\n", err)
+			defer close(done)
+			_, err := io.Copy(stdoutW, stdout)
+			// Closed by Run, to stop waiting for the output (see outputGrace).
+			if err != nil && !errors.Is(err, os.ErrClosed) {
+				errCh <- fmt.Errorf("copying stdout: %s", err)
 			}
 		}()
 	}
+	return done
+}
+
+// How long to keep reading timing records once the command has terminated.
+const timingGrace = 100 * time.Millisecond
+
+// How long to keep reading the output of the command once it has been killed
+// because its context is done.
+const outputGrace = 100 * time.Millisecond
+
+// setupTimingRecords feeds to obs the timing records read from timingR, if not nil.
+// Errors are sent to errCh.
+// The returned channel is closed when timingR has been drained.
+func setupTimingRecords(timingR *os.File, obs observer, errCh chan<- error) <-chan struct{} {
+	done := make(chan struct{})
+	if timingR == nil {
+		close(done)
+		return done
+	}
+	go func() {
+		defer close(done)
+		defer timingR.Close()
+		if err := observeOutput(obs, "TIMEIT_FD", timingR, io.Discard); err != nil {
+			errCh <- err
+		}
+	}()
+	return done
 }
 
+// setupSignalHandling reports and ignores SIGINT, until stop is closed; from then
+// on, SIGINT is ignored silently.
+// The returned channel is closed when the handler has stopped.
+//
 // We are in the parent, after having started the child.
 // Ignoring SIGINT as the original /usr/bin/time does with
 // signal.Ignore(os.Interrupt) has subtle side effects with the tests.
 // Thus, we do the equivalent with a do-nothing signal handler.
-func setupSignalHandling(out printFn) {
+func setupSignalHandling(stop <-chan struct{}, out printFn) <-chan struct{} {
+	done := make(chan struct{})
 	signalCh := make(chan os.Signal, 1)
 	signal.Notify(signalCh, os.Interrupt)
 	go func() {
+		defer close(done)
 		count := 0
 		for {
-			sig := <-signalCh
-			count++
-			out("timeit: got signal name=%s count=%d disposition=ignore\n", sig, count)
+			select {
+			case <-stop:
+				// Still registered with signal.Notify, further signals are dropped
+				// once signalCh is full.
+				return
+			case sig := <-signalCh:
+				count++
+				out("timeit: got signal name=%s count=%d disposition=ignore\n", sig, count)
+			}
 		}
 	}()
+	return done
 }
 
-func setupPeriodicTicker(t0 time.Time, dur time.Duration, summarize bool, records *records, out printFn) func() {
+// setupPeriodicTicker prints the ticker each dur, until stop is closed.
+// The returned channel is closed when the ticker has stopped.
+func setupPeriodicTicker(stop <-chan struct{}, t0 time.Time, dur time.Duration, summarize bool,
+	records *records, out printFn,
+) <-chan struct{} {
+	done := make(chan struct{})
 	if dur == 0 {
-		return func() {}
+		close(done)
+		return done
 	}
 
-	done := make(chan struct{})
 	ticker := time.NewTicker(dur)
-	dur100 := dur / 100
-	var bld strings.Builder
-	tw := tabwriter.NewWriter(&bld, 5, 0, 2, ' ', 0)
-	flying := make([]event, 0, 100)
+	tw := newTickerWriter()
 
 	go func() {
+		defer close(done)
 		for {
 			select {
 
-			case <-done:
+			case <-stop:
 				ticker.Stop()
 				return
 
 			case now := <-ticker.C:
-				fmt.Fprintf(&bld, "\ntimeit ticker: running for %s\n",
-					now.Sub(t0).Truncate(dur))
-				if summarize {
-					fmt.Fprintf(&bld, "in-flight:\n")
-
-					records.mu.Lock()
-					// From map to slice, so that we can sort by duration.
-					for _, evt := range records.flying {
-						flying = append(flying, evt)
-					}
-					records.mu.Unlock()
-
-					sort.Slice(flying, func(i, j int) bool {
-						return flying[i].started.Before(flying[j].started)
-					})
-					for i, evt := range flying {
-						elapsed := now.Sub(evt.started).Truncate(dur100)
-						fmt.Fprintf(tw, "    %4d\t%s\t%6v\n", i+1, evt.name, elapsed)
-					}
-					tw.Flush()
-				}
-				out("%s\n", bld.String())
-				bld.Reset()
-				// reset, keep allocated memory
-				flying = flying[:0]
+				out("%s\n", tw.ticker(now, t0, dur, summarize, records))
 			}
 		}
 	}()
 
-	return func() {
-		done <- struct{}{}
+	return done
+}
+
+// A tickerWriter formats the ticker, reusing its memory from one ticker to the
+// next.
+type tickerWriter struct {
+	bld    strings.Builder
+	tw     *tabwriter.Writer
+	flying []event
+}
+
+func newTickerWriter() *tickerWriter {
+	tw := &tickerWriter{flying: make([]event, 0, 100)}
+	tw.tw = tabwriter.NewWriter(&tw.bld, 5, 0, 2, ' ', 0)
+	return tw
+}
+
+// ticker returns the ticker at time now, for a ticker each dur since t0. If
+// summarize, the ticker shows also the progress and the flights in flight.
+func (tw *tickerWriter) ticker(now time.Time, t0 time.Time, dur time.Duration,
+	summarize bool, records *records,
+) string {
+	dur100 := dur / 100
+	bld := &tw.bld
+	// reset, keep allocated memory
+	defer func() {
+		bld.Reset()
+		tw.flying = tw.flying[:0]
+	}()
+
+	fmt.Fprintf(bld, "\ntimeit ticker: running for %s\n", now.Sub(t0).Truncate(dur))
+	if summarize {
+		records.mu.Lock()
+		// From map to slice, so that we can sort by duration.
+		for _, evt := range records.flying {
+			tw.flying = append(tw.flying, evt)
+		}
+		landedN, totalN := records.done, records.total
+		crashed := crashedWorkers(records.crashes)
+		records.mu.Unlock()
+		flying := tw.flying
+
+		if totalN > 0 {
+			fmt.Fprintf(bld, "progress: %s\n", progress(landedN, totalN, now.Sub(t0), dur))
+		}
+		if len(crashed) > 0 {
+			fmt.Fprintf(bld, "crashed workers: %s\n", strings.Join(crashed, ", "))
+		}
+		fmt.Fprintf(bld, "in-flight:\n")
+
+		if hierarchical(flying) {
+			var counter int
+			writeFlying(tw.tw, newTree(flying), 0, now, dur100, &counter)
+		} else {
+			sort.Slice(flying, func(i, j int) bool {
+				return flying[i].started.Before(flying[j].started)
+			})
+			for i, evt := range flying {
+				elapsed := now.Sub(evt.started).Truncate(dur100)
+				fmt.Fprintf(tw.tw, "    %4d\t%s\t%6v\n", i+1, evt.displayName(), elapsed)
+			}
+		}
+		tw.tw.Flush()
+	}
+	return bld.String()
+}
+
+// crashedWorkers returns the workers in crashes, each one once, in crash order.
+func crashedWorkers(crashes []crash) []string {
+	var workers []string
+	for _, crash := range crashes {
+		if !slices.Contains(workers, crash.worker) {
+			workers = append(workers, crash.worker)
+		}
+	}
+	return workers
+}
+
+// retriedFlights returns the sorted names of the flights with more than one attempt.
+func retriedFlights(records *records) []string {
+	var names []string
+	for name, attempts := range records.attempts {
+		if attempts > 1 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// progress returns a human-readable summary of done out of total, with an estimate
+// of the remaining time based on the elapsed time, rounded to precision.
+func progress(done int, total int, elapsed time.Duration, precision time.Duration) string {
+	summary := fmt.Sprintf("%d/%d (%d%%)", done, total, done*100/total)
+	if done == 0 || done >= total {
+		return summary
 	}
+	// Divide first: elapsed times the remaining flights could overflow.
+	eta := elapsed / time.Duration(done) * time.Duration(total-done)
+	return fmt.Sprintf("%s, eta %s", summary, eta.Truncate(precision))
 }
 
 func extractStatus(procState *os.ProcessState, waitErr error) (string, int) {
@@ -4,14 +4,18 @@
 package timeit_test
 
 import (
+	"fmt"
 	"os"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/marco-m/timeit/pkg/pytestsim"
 	"github.com/marco-m/timeit/pkg/sleepit"
 	"github.com/marco-m/timeit/pkg/timeit"
 
 	"github.com/rogpeppe/go-internal/testscript"
+	"gotest.tools/v3/assert"
 )
 
 func TestMain(m *testing.M) {
@@ -19,9 +23,29 @@ func TestMain(m *testing.M) {
 		"timeit":    timeit.Main,
 		"sleepit":   sleepit.Main,
 		"pytestsim": pytestsim.Main,
+		"timingrec": timingRec,
 	}))
 }
 
+// timingRec writes each of its arguments as a timing record to the file descriptor
+// in environment variable TIMEIT_FD, sleeping 10ms between records.
+func timingRec() int {
+	fd, err := strconv.Atoi(os.Getenv("TIMEIT_FD"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "timingrec: TIMEIT_FD:", err)
+		return 1
+	}
+	fi := os.NewFile(uintptr(fd), "timing")
+	for _, rec := range os.Args[1:] {
+		if _, err := fmt.Fprintln(fi, rec); err != nil {
+			fmt.Fprintln(os.Stderr, "timingrec:", err)
+			return 1
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return 0
+}
+
 // NOTE Since kong, used by timeit, calls os.Exit directly, we miss some coverage
 // information :-(
 func TestScriptTimeit(t *testing.T) {
@@ -29,3 +53,12 @@ func TestScriptTimeit(t *testing.T) {
 		Dir: "testdata",
 	})
 }
+
+func TestProgress(t *testing.T) {
+	assert.Equal(t, timeit.Progress(0, 40, time.Minute, time.Second), "0/40 (0%)")
+	assert.Equal(t, timeit.Progress(10, 40, time.Minute, time.Second), "10/40 (25%), eta 3m0s")
+	assert.Equal(t, timeit.Progress(40, 40, time.Minute, time.Second), "40/40 (100%)")
+	// elapsed times the remaining flights would overflow.
+	assert.Equal(t, timeit.Progress(100_000, 400_000, 10*time.Hour, time.Second),
+		"100000/400000 (25%), eta 30h0m0s")
+}
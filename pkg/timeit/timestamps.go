@@ -0,0 +1,113 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Modes of --timestamps.
+const (
+	timestampsElapsed = "elapsed"
+	timestampsWall    = "wall"
+)
+
+// Tags of the timestamps, telling apart the lines of stdout and of stderr also
+// without color.
+const (
+	timestampTagStdout = "|"
+	timestampTagStderr = "!"
+)
+
+// A timestamper prefixes each line written to it with the time of its first byte,
+// elapsed since t0 or wall clock, and with a tag (see --timestamps):
+//
+//	00:01:02.345 | a line of stdout
+//	00:01:02.346 ! a line of stderr
+type timestamper struct {
+	dst   io.Writer
+	t0    time.Time
+	wall  bool
+	tag   string
+	color *color.Color
+	now   func() time.Time
+	// True if the next byte starts a line.
+	atLineStart bool
+	buf         []byte
+}
+
+// newTimestamper returns a timestamper for stream (muxStdout or muxStderr),
+// writing to dst, with timestamps of mode (timestampsElapsed or timestampsWall).
+func newTimestamper(dst io.Writer, mode string, t0 time.Time, stream int) *timestamper {
+	ts := &timestamper{
+		dst:         dst,
+		t0:          t0,
+		wall:        mode == timestampsWall,
+		tag:         timestampTagStdout,
+		color:       color.New(color.Faint),
+		now:         time.Now,
+		atLineStart: true,
+	}
+	if stream == muxStderr {
+		ts.tag = timestampTagStderr
+		ts.color = color.New(color.FgRed)
+	}
+	return ts
+}
+
+// Write writes p to dst in a single write, prefixing each line.
+func (ts *timestamper) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	n := len(p)
+	prefix := ts.prefix(ts.now())
+	ts.buf = ts.buf[:0]
+	for len(p) > 0 {
+		if ts.atLineStart {
+			ts.buf = append(ts.buf, prefix...)
+			ts.atLineStart = false
+		}
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			ts.buf = append(ts.buf, p...)
+			break
+		}
+		ts.buf = append(ts.buf, p[:i+1]...)
+		p = p[i+1:]
+		ts.atLineStart = true
+	}
+	if _, err := ts.dst.Write(ts.buf); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// prefix returns the prefix of a line starting at now.
+func (ts *timestamper) prefix(now time.Time) string {
+	var stamp string
+	if ts.wall {
+		stamp = now.Format("15:04:05.000")
+	} else {
+		stamp = formatElapsed(now.Sub(ts.t0))
+	}
+	return ts.color.Sprint(stamp+" "+ts.tag) + " "
+}
+
+// formatElapsed returns d as hh:mm:ss.mmm.
+func formatElapsed(d time.Duration) string {
+	d = d.Truncate(time.Millisecond)
+	h := d / time.Hour
+	d -= h * time.Hour
+	m := d / time.Minute
+	d -= m * time.Minute
+	s := d / time.Second
+	d -= s * time.Second
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", h, m, s, d/time.Millisecond)
+}
@@ -0,0 +1,69 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"gotest.tools/v3/assert"
+)
+
+func TestTimestamper(t *testing.T) {
+	defer func(noColor bool) { color.NoColor = noColor }(color.NoColor)
+	color.NoColor = true
+
+	t0 := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	stdout := newTimestamper(&buf, timestampsElapsed, t0, muxStdout)
+	stderr := newTimestamper(&buf, timestampsElapsed, t0, muxStderr)
+	now := t0
+	stdout.now = func() time.Time { return now }
+	stderr.now = func() time.Time { return now }
+
+	fmt.Fprint(stdout, "one\ntwo\nthree begins")
+	now = now.Add(1500 * time.Millisecond)
+	fmt.Fprint(stdout, " and ends\n")
+	fmt.Fprint(stderr, "error\n")
+	now = now.Add(2*time.Hour + 3*time.Minute)
+	fmt.Fprint(stdout, "\n")
+
+	assert.Equal(t, buf.String(), ""+
+		"00:00:00.000 | one\n"+
+		"00:00:00.000 | two\n"+
+		"00:00:00.000 | three begins and ends\n"+
+		"00:00:01.500 ! error\n"+
+		"02:03:01.500 | \n")
+}
+
+func TestTimestamperWall(t *testing.T) {
+	defer func(noColor bool) { color.NoColor = noColor }(color.NoColor)
+	color.NoColor = true
+
+	t0 := time.Date(2026, 1, 1, 10, 0, 0, 0, time.Local)
+	var buf bytes.Buffer
+	ts := newTimestamper(&buf, timestampsWall, t0, muxStderr)
+	ts.now = func() time.Time { return t0.Add(61*time.Second + 7*time.Millisecond) }
+
+	fmt.Fprint(ts, "hello\n")
+
+	assert.Equal(t, buf.String(), "10:01:01.007 ! hello\n")
+}
+
+func TestTimestamperColor(t *testing.T) {
+	defer func(noColor bool) { color.NoColor = noColor }(color.NoColor)
+	color.NoColor = false
+
+	var buf bytes.Buffer
+	t0 := time.Now()
+	ts := newTimestamper(&buf, timestampsElapsed, t0, muxStderr)
+	ts.now = func() time.Time { return t0 }
+
+	fmt.Fprint(ts, "hello\n")
+
+	assert.Equal(t, buf.String(), "\x1b[31m00:00:00.000 !\x1b[0m hello\n")
+}
@@ -0,0 +1,162 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A transcript records a run to a file (see --log), one record per line:
+//
+//	SECONDS TAG TEXT
+//
+// SECONDS is the time since the start of the command (monotonic clock), with
+// microsecond precision. TAG is one of:
+//
+//	cmd     the command, as Go (double-quoted) strings separated by spaces
+//	start   the wall clock at the start of the command, as RFC 3339
+//	out     a line of the stdout of the command
+//	err     a line of the stderr of the command
+//	timeit  a line of the output of timeit while the command runs (ticker, signals)
+//	result  a line of the results
+//
+// TEXT is the line as written, without the terminating newline and without
+// color. A line of the command is recorded when it is complete, since that is
+// when an observer sees it. The first line of the file is a comment, starting
+// with #. For example:
+//
+//	# timeit transcript 1
+//	0.000000 cmd "pytest" "-v"
+//	0.000000 start 2026-10-18T22:26:17.123456Z
+//	0.412345 out test_fruits.py::test_apple PASSED
+//	1.000103 timeit timeit ticker: running for 1s
+//	1.532210 result     command succeeded
+type transcript struct {
+	mu  sync.Mutex
+	w   *bufio.Writer
+	t0  time.Time
+	err error
+}
+
+// transcriptHeader is the first line of a transcript.
+const transcriptHeader = "# timeit transcript 1"
+
+// Tags of the records of a transcript.
+const (
+	tagCmd    = "cmd"
+	tagStart  = "start"
+	tagStdout = "out"
+	tagStderr = "err"
+	tagTimeit = "timeit"
+	tagResult = "result"
+)
+
+// newTranscript returns a transcript of the command started at t0, writing to w.
+func newTranscript(w io.Writer, t0 time.Time, command []string) *transcript {
+	tr := &transcript{w: bufio.NewWriter(w), t0: t0}
+	quoted := make([]string, 0, len(command))
+	for _, arg := range command {
+		quoted = append(quoted, strconv.Quote(arg))
+	}
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if _, err := fmt.Fprintln(tr.w, transcriptHeader); err != nil {
+		tr.err = err
+	}
+	tr.record(tagCmd, t0, []byte(strings.Join(quoted, " ")))
+	tr.record(tagStart, t0, []byte(t0.Format(time.RFC3339Nano)))
+	return tr
+}
+
+// text records each line of text with tag.
+func (tr *transcript) text(tag string, text string) {
+	now := time.Now()
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	text = strings.TrimSuffix(text, "\n")
+	for _, line := range strings.Split(text, "\n") {
+		tr.record(tag, now, []byte(line))
+	}
+}
+
+// record writes a record. Must be called with tr.mu held.
+func (tr *transcript) record(tag string, now time.Time, line []byte) {
+	if tr.err != nil {
+		return
+	}
+	secs := strconv.FormatFloat(now.Sub(tr.t0).Seconds(), 'f', 6, 64)
+	tr.w.WriteString(secs)
+	tr.w.WriteByte(' ')
+	tr.w.WriteString(tag)
+	tr.w.WriteByte(' ')
+	tr.w.Write(line)
+	if err := tr.w.WriteByte('\n'); err != nil {
+		tr.err = err
+	}
+}
+
+// flush writes the buffered records and returns the first error, if any.
+func (tr *transcript) flush() error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if tr.err == nil {
+		tr.err = tr.w.Flush()
+	}
+	if tr.err != nil {
+		return fmt.Errorf("writing log: %s", tr.err)
+	}
+	return nil
+}
+
+// stream returns a writer that records each line written to it with tag.
+func (tr *transcript) stream(tag string) *transcriptWriter {
+	return &transcriptWriter{tr: tr, tag: tag}
+}
+
+// A transcriptWriter records the lines of a stream of the command in a
+// transcript. Call flush to record the last line, if not terminated.
+type transcriptWriter struct {
+	tr      *transcript
+	tag     string
+	partial []byte
+}
+
+func (tw *transcriptWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	now := time.Now()
+	tw.tr.mu.Lock()
+	defer tw.tr.mu.Unlock()
+	for {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			break
+		}
+		line := p[:i]
+		if len(tw.partial) > 0 {
+			line = append(tw.partial, line...)
+			tw.partial = tw.partial[:0]
+		}
+		tw.tr.record(tw.tag, now, line)
+		p = p[i+1:]
+	}
+	tw.partial = append(tw.partial, p...)
+	// The transcript is not the terminal: never fail the copy of the output.
+	return n, nil
+}
+
+func (tw *transcriptWriter) flush() {
+	tw.tr.mu.Lock()
+	defer tw.tr.mu.Unlock()
+	if len(tw.partial) > 0 {
+		tw.tr.record(tw.tag, time.Now(), tw.partial)
+		tw.partial = tw.partial[:0]
+	}
+}
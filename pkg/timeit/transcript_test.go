@@ -0,0 +1,71 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestTranscript(t *testing.T) {
+	var buf bytes.Buffer
+	tr := newTranscript(&buf, time.Now(), []string{"pytest", "-k", "apple or banana"})
+	stdout := tr.stream(tagStdout)
+	stderr := tr.stream(tagStderr)
+
+	fmt.Fprint(stdout, "one\ntwo begins")
+	fmt.Fprint(stderr, "error\n")
+	fmt.Fprint(stdout, " and ends\r\nthree, not terminated")
+	tr.text(tagTimeit, "\ntimeit ticker: running for 1s\n")
+	stdout.flush()
+	stderr.flush()
+	tr.text(tagResult, "\ntimeit results:\n    command succeeded\n")
+	assert.NilError(t, tr.flush())
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.Equal(t, lines[0], transcriptHeader)
+	secsRe := regexp.MustCompile(`^\d+\.\d{6} `)
+	var records []string
+	for _, line := range lines[1:] {
+		assert.Assert(t, secsRe.MatchString(line), "line: %q", line)
+		records = append(records, secsRe.ReplaceAllString(line, ""))
+	}
+	assert.Assert(t, regexp.MustCompile(`^start \d{4}-\d\d-\d\dT`).MatchString(records[1]),
+		"record: %q", records[1])
+	records = append(records[:1], records[2:]...)
+	assert.DeepEqual(t, records, []string{
+		`cmd "pytest" "-k" "apple or banana"`,
+		"out one",
+		"err error",
+		"out two begins and ends\r",
+		"timeit ",
+		"timeit timeit ticker: running for 1s",
+		"out three, not terminated",
+		"result ",
+		"result timeit results:",
+		"result     command succeeded",
+	})
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, fmt.Errorf("disk full")
+}
+
+func TestTranscriptWriteError(t *testing.T) {
+	tr := newTranscript(failingWriter{}, time.Now(), []string{"true"})
+	n, err := tr.stream(tagStdout).Write([]byte("hello\n"))
+
+	// The copy of the output of the command goes on.
+	assert.NilError(t, err)
+	assert.Equal(t, n, 6)
+	assert.Error(t, tr.flush(), "writing log: disk full")
+}
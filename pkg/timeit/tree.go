@@ -0,0 +1,147 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A node of the tree of flights, built from the paths of the events. The leaves
+// are flights; an inner node is a group of flights (for example a pytest file)
+// and can be also a flight itself (for example a go package).
+type node struct {
+	label string
+	// The flight of this node, if any.
+	evt      *event
+	children []*node
+	byLabel  map[string]*node
+	// Span of the flights in the subtree. For flights in flight, finished is zero.
+	started  time.Time
+	finished time.Time
+	// Number of flights in the subtree.
+	count int
+}
+
+// hierarchical returns true if at least one of events is nested in a group.
+func hierarchical(events []event) bool {
+	for _, evt := range events {
+		if len(evt.path) > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// newTree returns the root of the tree of events. An event without a path is a
+// child of the root.
+func newTree(events []event) *node {
+	root := &node{}
+	for i := range events {
+		evt := &events[i]
+		path := evt.path
+		if len(path) == 0 {
+			path = []string{evt.name}
+		}
+		if evt.attempt > 1 {
+			path = append(path[:len(path)-1:len(path)-1],
+				fmt.Sprintf("%s (attempt %d)", path[len(path)-1], evt.attempt))
+		}
+		n := root
+		n.update(evt)
+		for _, label := range path {
+			n = n.child(label)
+			n.update(evt)
+		}
+		n.evt = evt
+	}
+	return root
+}
+
+func (n *node) child(label string) *node {
+	if c, ok := n.byLabel[label]; ok {
+		return c
+	}
+	if n.byLabel == nil {
+		n.byLabel = make(map[string]*node)
+	}
+	c := &node{label: label}
+	n.byLabel[label] = c
+	n.children = append(n.children, c)
+	return c
+}
+
+// update extends the span of n to include evt.
+func (n *node) update(evt *event) {
+	n.count++
+	if n.started.IsZero() || evt.started.Before(n.started) {
+		n.started = evt.started
+	}
+	if evt.finished.After(n.finished) {
+		n.finished = evt.finished
+	}
+}
+
+// duration returns the span of the subtree of n. For flights in flight, the span
+// extends until now.
+func (n *node) duration(now time.Time) time.Duration {
+	if n.finished.IsZero() {
+		return now.Sub(n.started)
+	}
+	return n.finished.Sub(n.started)
+}
+
+// writeGroups writes to w the groups below n (the inner nodes, or the nodes
+// without a flight), each with its total duration and number of flights, sorted
+// by duration.
+func writeGroups(w io.Writer, n *node, depth int, precision time.Duration) {
+	children := make([]*node, 0, len(n.children))
+	for _, c := range n.children {
+		if len(c.children) > 0 || c.evt == nil {
+			children = append(children, c)
+		}
+	}
+	sort.SliceStable(children, func(i, j int) bool {
+		return children[i].duration(time.Time{}) > children[j].duration(time.Time{})
+	})
+	for _, c := range children {
+		fmt.Fprintf(w, "        %s%s\t%8v\t%s\n", strings.Repeat("  ", depth), c.label,
+			c.duration(time.Time{}).Truncate(precision), plural(c.count, "flight"))
+		writeGroups(w, c, depth+1, precision)
+	}
+}
+
+// writeFlying writes to w the tree of flights in flight below n, sorted by age
+// (oldest first). The flights are numbered starting from *counter.
+func writeFlying(w io.Writer, n *node, depth int, now time.Time, precision time.Duration,
+	counter *int,
+) {
+	children := make([]*node, len(n.children))
+	copy(children, n.children)
+	sort.SliceStable(children, func(i, j int) bool {
+		return children[i].started.Before(children[j].started)
+	})
+	indent := strings.Repeat("  ", depth)
+	for _, c := range children {
+		elapsed := now.Sub(c.started).Truncate(precision)
+		if c.evt != nil {
+			*counter++
+			fmt.Fprintf(w, "    %4d\t%s%s\t%6v\n", *counter, indent, c.label, elapsed)
+		} else {
+			fmt.Fprintf(w, "    %4s\t%s%s\t%6v\n", "", indent, c.label, elapsed)
+		}
+		writeFlying(w, c, depth+1, now, precision, counter)
+	}
+}
+
+// plural returns count followed by noun, in plural form if needed.
+func plural(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
@@ -0,0 +1,64 @@
+// This code is released under the MIT License
+// Copyright (c) 2026 Marco Molteni and the timeit contributors.
+
+package timeit
+
+import (
+	"strings"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestWriteGroups(t *testing.T) {
+	t0 := time.Now()
+	records := feed(t0, newPytestObserver, config{}, []line{
+		{0, "test_fruits.py::test_apple"},
+		{0, "test_herbs.py::TestBasil::test_leaf[a::b]"},
+		{1 * time.Second, "test_fruits.py::test_banana"},
+		{2 * time.Second, "[gw1] [ 25%] PASSED test_herbs.py::TestBasil::test_leaf[a::b]"},
+		{3 * time.Second, "[gw2] [ 50%] PASSED test_fruits.py::test_apple"},
+		{5 * time.Second, "[gw1] [ 75%] FAILED test_fruits.py::test_banana"},
+	})
+	landed := records.landed
+	assert.Assert(t, hierarchical(landed))
+
+	var bld strings.Builder
+	tw := tabwriter.NewWriter(&bld, 5, 0, 2, ' ', 0)
+	writeGroups(tw, newTree(landed), 0, time.Millisecond)
+	tw.Flush()
+
+	want := "" +
+		"        test_fruits.py        5s  2 flights\n" +
+		"        test_herbs.py         2s  1 flight\n" +
+		"          TestBasil           2s  1 flight\n"
+	assert.Equal(t, bld.String(), want)
+}
+
+func TestWriteFlying(t *testing.T) {
+	t0 := time.Now()
+	records := feed(t0, newSectionsObserver, config{}, []line{
+		{0, "::group::Build"},
+		{1 * time.Second, "::group::compile"},
+		{2 * time.Second, "::group::Lint"},
+	})
+	var inFlight []event
+	for _, evt := range records.flying {
+		inFlight = append(inFlight, evt)
+	}
+	assert.Assert(t, hierarchical(inFlight))
+
+	var bld strings.Builder
+	tw := tabwriter.NewWriter(&bld, 5, 0, 2, ' ', 0)
+	var counter int
+	writeFlying(tw, newTree(inFlight), 0, t0.Add(10*time.Second), time.Second, &counter)
+	tw.Flush()
+
+	want := "" +
+		"       1  Build         10s\n" +
+		"       2    compile      9s\n" +
+		"       3      Lint       8s\n"
+	assert.Equal(t, bld.String(), want)
+}